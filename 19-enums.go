@@ -1,9 +1,16 @@
 // Go doesn't have an enum type as a distinct lang feature, but enums
 // can be implemented easily.
+//
+// The `String` method (and parsing, validation, JSON, etc.) of `ServerState` is generated in `serverstate_enum.go`
+// by running `go generate` (see `cmd/enumgen`), from the constants below. See `19-enums_test.go` for its tests.
 package main
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+)
 
+//go:generate go run ./cmd/enumgen -type=ServerState -trimprefix=State
 type ServerState int
 
 // Creates possible values for ServerState defined as constants.
@@ -15,21 +22,20 @@ const (
 	StateRetrying
 )
 
+// The names used to be in a hand-maintained map:
+//
+//	var stateName = map[ServerState]string{StateIdle: "idle", StateConnected: "connected", ...}
+//	func (ss ServerState) String() string { return stateName[ss] }
+//
 // Issue: does not enforce value for all ServerState keys (can omit a ServerState key) -> error prone
 // Issue: can add any int key --> error prone
-var stateName = map[ServerState]string{
-	StateIdle:      "idle",
-	StateConnected: "connected",
-	StateError:     "error",
-	StateRetrying:  "retrying",
-}
-
-// - Below method allows to claim ServerState implements `fmt.Stringer` interface,
+//
+// They are now generated from the constants themselves: every constant gets a name (generation fails otherwise),
+// and `IsValid`/`ParseServerState` reject values/names that are not one of the constants.
+//
+// - The generated `String` method allows to claim ServerState implements `fmt.Stringer` interface,
 // allowing values of ServerState to be printed out or converted to strings
-// - Note that below method is implemented on a type that is not a struct!
-func (ss ServerState) String() string {
-	return stateName[ss]
-}
+// - Note that it is implemented on a type that is not a struct!
 
 func enums_main() {
 	// Cannot pass an int as the argument to `transition` --> compiler will give an error
@@ -41,6 +47,16 @@ func enums_main() {
 
 	ns2 := transition(ns)
 	fmt.Println(ns2)
+
+	// Generated by `go generate`
+	fmt.Println(ServerStateValues(), ServerState(42), ServerState(42).IsValid())
+	fmt.Println(ParseServerState("retrying"))
+	fmt.Println(ParseServerState("sleeping"))
+	data, _ := json.Marshal(map[string]ServerState{"state": StateError})
+	fmt.Println(string(data))
+	var decoded struct{ State ServerState }
+	fmt.Println(json.Unmarshal([]byte(`{"State": "connected"}`), &decoded), decoded.State)
+	fmt.Println(json.Unmarshal([]byte(`{"State": 1}`), &decoded))
 }
 
 func transition(s ServerState) ServerState {
@@ -52,7 +68,7 @@ func transition(s ServerState) ServerState {
 	case StateError:
 		return StateError
 	default:
-		// s will be empty, will not print anything (because no ServerState matches its value --> i tried with constant > 3)
+		// No ServerState constant matches its value (i tried with constant > 3) --> printed as `ServerState(42)`
 		panic(fmt.Errorf("unknown state: %s", s))
 	}
 }
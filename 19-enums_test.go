@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+// The generated names of both enums round trip, whatever the constants
+func TestServerStateNames(t *testing.T) {
+	for _, s := range ServerStateValues() {
+		if parsed, err := ParseServerState(s.String()); err != nil || parsed != s {
+			t.Errorf("ParseServerState(%q) = %v, %v", s, parsed, err)
+		}
+	}
+	for _, e := range ServerEventValues() {
+		if parsed, err := ParseServerEvent(e.String()); err != nil || parsed != e {
+			t.Errorf("ParseServerEvent(%q) = %v, %v", e, parsed, err)
+		}
+	}
+}
@@ -5,6 +5,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"sync"
 )
 
@@ -29,6 +30,15 @@ func (c *Container) inc(name string) {
 }
 
 func main() {
+	// `go run . shapes [flags] [file]` runs the `shapes` command of `63-shapes-command` chapter instead
+	if len(os.Args) > 1 && os.Args[1] == "shapes" {
+		if err := shapesCommand(os.Args[2:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+			fmt.Fprintln(os.Stderr, "shapes:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Note that the zero value of a mutex is usable as-is, so no initialization is required here.
 	c := Container{
 		counters: map[string]int{"a": 0, "b": 0},
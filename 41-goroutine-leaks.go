@@ -0,0 +1,235 @@
+// A goroutine "leaks" when it is started but never finishes: it stays blocked forever on a channel,
+// a lock, a timer, etc. Leaked goroutines are never garbage collected (together with everything they reference).
+//
+// A few previous chapters leak on purpose:
+// - `channels_main` leaves the sender blocked on `"pong"` (no receiver)
+// - `timer_main` leaves a goroutine blocked forever on `<-timer2.C` (timer stopped, channel never written)
+// - `rate_limiting_main` never stops the `time.Tick` goroutine feeding `burstyLimiter`
+//
+// Here we build a small leak detector (`VerifyNoLeaks`) meant to be called at the start of a test:
+// it snapshots all goroutines (through `runtime.Stack`), and when the test ends, compares the
+// goroutines still alive against that snapshot. A goroutine that is new (not in the snapshot)
+// and not a known system goroutine is reported with the function that created it and the line it is blocked at.
+//
+// See `41-goroutine-leaks_test.go` for tests using it (`go test -run Leak`).
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// How long we keep retrying before declaring a goroutine leaked.
+// A goroutine might just be finishing its exec when the test ends (ex: between `wg.Done()` and its `return`),
+// so we give it some time instead of failing right away.
+const leakGracePeriod = time.Second
+
+// Subset of `testing.TB` used by `VerifyNoLeaks`.
+//
+// `*testing.T` and `*testing.B` implement it, but keeping our own interface allows to use
+// the detector outside of `go test` too (see `leakReporter` below).
+type leakT interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Cleanup(func())
+}
+
+// Functions that, if found anywhere in a goroutine's stack, mean the goroutine belongs to the
+// runtime or the test framework (and is therefore not a leak of the code under test).
+var ignoredLeakFuncs = []string{
+	"testing.RunTests",
+	"testing.(*T).Run",
+	"testing.(*T).Parallel",
+	"testing.(*M).Run",
+	"testing.runFuzzing",
+	"testing.tRunner.func1",
+	"os/signal.signal_recv",
+	"os/signal.loop",
+	"runtime.ensureSigM",
+	"runtime.goexit0",
+	"runtime/trace.Start",
+	"created by runtime.gc",
+	"created by runtime.init",
+}
+
+// A parsed goroutine from the `runtime.Stack` dump
+type goroutineInfo struct {
+	id    string
+	state string // why it is parked (ex: "chan receive", "select", "sleep")
+	// Function which started the goroutine with the `go` keyword (the "created by" line)
+	creator string
+	// First frame outside of the runtime: the line of our code where the goroutine is blocked
+	blockedAt string
+	stack     string
+}
+
+func (g goroutineInfo) String() string {
+	return fmt.Sprintf("goroutine %s [%s]\n\tcreated by: %s\n\tblocked at: %s", g.id, g.state, g.creator, g.blockedAt)
+}
+
+// Returns the dump of all goroutines' stacks.
+//
+// `runtime.Stack` writes at most `len(buf)` bytes, so we keep doubling the buffer
+// until the whole dump fits (`n < len(buf)`).
+func allStacks() string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// Parses a dump produced by `runtime.Stack(buf, true)`.
+//
+// Each goroutine is separated by an empty line and looks like:
+//
+//	goroutine 7 [chan receive]:
+//	main.channels_main.func1()
+//		/path/25-channels.go:28 +0x7e
+//	created by main.channels_main in goroutine 1
+//		/path/25-channels.go:25 +0x6a
+func parseGoroutines(dump string) []goroutineInfo {
+	var gs []goroutineInfo
+	for _, block := range strings.Split(strings.TrimSpace(dump), "\n\n") {
+		lines := strings.Split(block, "\n")
+		header, ok := strings.CutPrefix(lines[0], "goroutine ")
+		if !ok {
+			continue
+		}
+
+		g := goroutineInfo{stack: block}
+		g.id, g.state, _ = strings.Cut(header, " ")
+		// "[chan receive, 2 minutes]:" --> "chan receive"
+		g.state = strings.TrimSuffix(strings.TrimPrefix(g.state, "["), "]:")
+		g.state, _, _ = strings.Cut(g.state, ",")
+
+		// Frames come in pairs of lines: function, then `\tfile:line +offset`
+		for i := 1; i+1 < len(lines); i += 2 {
+			fn, loc := lines[i], strings.TrimSpace(lines[i+1])
+			loc, _, _ = strings.Cut(loc, " +")
+
+			if creator, ok := strings.CutPrefix(fn, "created by "); ok {
+				creator, _, _ = strings.Cut(creator, " in goroutine")
+				g.creator = creator + " (" + loc + ")"
+				continue
+			}
+			if g.blockedAt == "" && !strings.HasPrefix(fn, "runtime.") {
+				g.blockedAt = fn + " (" + loc + ")"
+			}
+		}
+		gs = append(gs, g)
+	}
+	return gs
+}
+
+func isSystemGoroutine(g goroutineInfo) bool {
+	for _, fn := range ignoredLeakFuncs {
+		if strings.Contains(g.stack, fn) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the goroutines alive now that are neither in `before` nor system goroutines
+func leakedGoroutines(before map[string]bool) []goroutineInfo {
+	var leaks []goroutineInfo
+	for _, g := range parseGoroutines(allStacks()) {
+		if !before[g.id] && !isSystemGoroutine(g) {
+			leaks = append(leaks, g)
+		}
+	}
+	return leaks
+}
+
+// Same as `leakedGoroutines` but keeps retrying (with a growing pause) until no more
+// leaks are found or `grace` is elapsed.
+func waitForLeaks(before map[string]bool, grace time.Duration) []goroutineInfo {
+	deadline := time.Now().Add(grace)
+	pause := time.Millisecond
+	for {
+		leaks := leakedGoroutines(before)
+		if len(leaks) == 0 || time.Now().After(deadline) {
+			return leaks
+		}
+		time.Sleep(pause)
+		pause = min(2*pause, 100*time.Millisecond)
+	}
+}
+
+// Snapshots the goroutines currently running and registers a cleanup (run at the end of the test)
+// reporting every goroutine started since then and still alive after `leakGracePeriod`.
+//
+// Usage, at the very beginning of a test:
+//
+//	func TestWorkerPool(t *testing.T) {
+//		VerifyNoLeaks(t)
+//		...
+//	}
+func VerifyNoLeaks(t leakT) {
+	t.Helper()
+
+	before := map[string]bool{}
+	for _, g := range parseGoroutines(allStacks()) {
+		before[g.id] = true
+	}
+
+	t.Cleanup(func() {
+		for _, g := range waitForLeaks(before, leakGracePeriod) {
+			t.Errorf("leaked %v", g)
+		}
+	})
+}
+
+// Minimal `leakT` implementation printing to stdout, so we can run the detector over the previous chapters
+// without `go test`. Like `testing.T`, cleanups are run in reverse order of registration.
+type leakReporter struct {
+	name     string
+	failed   bool
+	cleanups []func()
+}
+
+func (r *leakReporter) Helper() {}
+
+func (r *leakReporter) Errorf(format string, args ...any) {
+	r.failed = true
+	fmt.Printf("--- FAIL %s: %s\n", r.name, fmt.Sprintf(format, args...))
+}
+
+func (r *leakReporter) Cleanup(fn func()) {
+	r.cleanups = append(r.cleanups, fn)
+}
+
+// Runs a chapter's main function like a test calling `VerifyNoLeaks`
+func runLeakChecked(name string, chapterMain func()) {
+	r := &leakReporter{name: name}
+	VerifyNoLeaks(r)
+
+	chapterMain()
+
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+	if !r.failed {
+		fmt.Printf("--- PASS %s\n", name)
+	}
+}
+
+func goroutine_leaks_main() {
+	// Leaks the "pong" sender
+	runLeakChecked("channels", channels_main)
+	// Leaks the goroutine waiting on the stopped timer
+	runLeakChecked("timers", timer_main)
+	// Leaks the `time.Tick` goroutine
+	runLeakChecked("rate_limiting", rate_limiting_main)
+
+	// Those ones wait for all their goroutines to finish --> no leak
+	runLeakChecked("worker_pools", worker_pools_main)
+	runLeakChecked("wait_groups", wait_groups_main)
+	runLeakChecked("tickers", tickers_main)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// `leakT` recording the errors instead of failing the test, to check that leaks ARE reported
+type fakeLeakT struct {
+	errors   []string
+	cleanups []func()
+}
+
+func (f *fakeLeakT) Helper() {}
+
+func (f *fakeLeakT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeLeakT) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+// Ends the fake test: runs the cleanups like `testing.T` does (last registered first)
+func (f *fakeLeakT) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+// Blocked until `release` is closed: a leak until then
+func blockUntil(release <-chan struct{}) {
+	<-release
+}
+
+func TestVerifyNoLeaksReportsLeak(t *testing.T) {
+	release := make(chan struct{})
+	// Released once the fake test has recorded the leak: left blocked, it would stay alive for the rest of the
+	// test binary, and could be reported by a later test's `VerifyNoLeaks`
+	t.Cleanup(func() { close(release) })
+
+	f := &fakeLeakT{}
+	VerifyNoLeaks(f)
+	go blockUntil(release)
+	f.finish()
+
+	if len(f.errors) != 1 {
+		t.Fatalf("got %d leaks, want 1: %q", len(f.errors), f.errors)
+	}
+	if !strings.Contains(f.errors[0], "created by: ") || !strings.Contains(f.errors[0], ".TestVerifyNoLeaksReportsLeak (") ||
+		!strings.Contains(f.errors[0], ".blockUntil(") {
+		t.Errorf("leak not attributed to blockUntil, started by the test: %s", f.errors[0])
+	}
+}
+
+// The concurrency chapters' examples all wait for their goroutines (the ones leaking on purpose are listed in
+// `41-goroutine-leaks.go`)
+func TestChaptersNoLeaks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the chapters sleep for ~20s in total")
+	}
+	for _, tc := range []struct {
+		name        string
+		chapterMain func()
+	}{
+		{"go_routines", go_routines_main},
+		{"buffered_channels", buffered_channels_main},
+		{"channel_synchronization", channel_synchronization_main},
+		{"channel_directions", channel_directions_main},
+		{"select", select_main},
+		{"timeouts", timeouts_main},
+		{"non_blocking_channels", non_blocking_channels_main},
+		{"closing_channels", closing_channels_main},
+		{"range_over_channels", range_over_channels_main},
+		{"tickers", tickers_main},
+		{"worker_pools", worker_pools_main},
+		{"wait_groups", wait_groups_main},
+		{"atomic_counters", atomic_counters_main},
+		{"futures", futures_main},
+		{"context_timeouts", context_timeouts_main},
+		{"dynamic_select", dynamic_select_main},
+		{"bounded_queue", bounded_queue_main},
+		{"sync_primitives", sync_primitives_main},
+		{"singleflight", singleflight_main},
+		{"concurrent_map", concurrent_map_main},
+		{"actors", actors_main},
+		{"tracing", tracing_main},
+		{"channel_iterators", channel_iterators_main},
+		{"parallel_map", parallel_map_main},
+	} {
+		// Not parallel: the goroutines of a subtest would be new (leaked) goroutines for the others
+		t.Run(tc.name, func(t *testing.T) {
+			VerifyNoLeaks(t)
+			tc.chapterMain()
+		})
+	}
+}
@@ -0,0 +1,298 @@
+// In `30-timeouts` chapter, each computation gets a buffered channel of capacity 1 that is written to exactly once:
+// that's an ad-hoc "future" (or "promise"), a placeholder for a value computed asynchronously.
+//
+// Here we build a generic `Future[T]` to make this pattern explicit and composable:
+// - `Async` starts the computation in a goroutine and immediately returns the future
+// - `Await` blocks until the result is available (or the caller's context is done)
+// - `Then`, `All`, `Any`, `Race` and `Timeout` combine futures into new futures
+//
+// Contrary to the channel version, the result is not SENT anywhere: it is stored in the future and a `done`
+// channel is closed. Closing never blocks, so the computing goroutine can never be stuck on a send nobody receives
+// (leaked sender), however many times (0, 1 or more) the future is awaited.
+//
+// The combinators are tested in `42-futures_test.go`, without leaks (see `41-goroutine-leaks` chapter).
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"time"
+)
+
+type Future[T any] struct {
+	// Closed once `val` and `err` are set.
+	// A closed channel can be received from by any number of goroutines (see `32-closing-channels` chapter).
+	done   chan struct{}
+	val    T
+	err    error
+	cancel context.CancelFunc
+}
+
+// Starts `fn` in a new goroutine and returns a future for its result.
+//
+// `fn` receives a context cancelled when the future is cancelled (`Cancel`, or a combinator like `Race`
+// not needing the result anymore), so it can stop working early.
+func Async[T any](fn func(ctx context.Context) (T, error)) *Future[T] {
+	return AsyncContext(context.Background(), fn)
+}
+
+// Same as `Async` but `fn`'s context derives from `parent` (cancelling `parent` cancels `fn`)
+func AsyncContext[T any](parent context.Context, fn func(ctx context.Context) (T, error)) *Future[T] {
+	ctx, cancel := context.WithCancel(parent)
+	f := &Future[T]{done: make(chan struct{}), cancel: cancel}
+
+	go func() {
+		// Release the context's resources once `fn` returns
+		defer cancel()
+		defer close(f.done)
+		// A panic in a goroutine crashes the whole program, so it is turned into the future's error instead
+		defer func() {
+			if r := recover(); r != nil {
+				f.err = fmt.Errorf("future panicked: %v", r)
+			}
+		}()
+
+		f.val, f.err = fn(ctx)
+	}()
+
+	return f
+}
+
+// Returns a future already completed with the given value/error (no goroutine involved)
+func Resolved[T any](val T, err error) *Future[T] {
+	f := &Future[T]{done: make(chan struct{}), val: val, err: err, cancel: func() {}}
+	close(f.done)
+	return f
+}
+
+// Returns a channel closed once the future is completed, so a future can be used in a `select`
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Asks the computation to stop (cancels its context). It does not wait for it to return.
+func (f *Future[T]) Cancel() {
+	f.cancel()
+}
+
+// Blocks until the future is completed and returns its result.
+//
+// If `ctx` is done first, `ctx`'s error is returned instead. The computation keeps going
+// (another caller might still await it), call `Cancel` to stop it.
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Returns a future of `fn` applied to `f`'s value, once `f` is completed.
+// If `f` fails, `fn` is not called and the new future fails with the same error.
+//
+// Fyi, methods cannot have their own type parameters in Go, which is why `Then` is a function
+// and not a method (`U` would be a type param of the method only).
+func Then[T, U any](f *Future[T], fn func(ctx context.Context, val T) (U, error)) *Future[U] {
+	return Async(func(ctx context.Context) (U, error) {
+		val, err := f.Await(ctx)
+		if err != nil {
+			var zero U
+			return zero, err
+		}
+		return fn(ctx, val)
+	})
+}
+
+// Returns a future of all the futures' values (in the same order).
+// Fails as soon as one of them fails, cancelling the others.
+func All[T any](futures ...*Future[T]) *Future[[]T] {
+	return Async(func(ctx context.Context) ([]T, error) {
+		// In completion order (not in the given order): a failure is seen as soon as it happens,
+		// even if the futures before it are still running
+		completed := 0
+		for f := range firstCompleted(ctx, futures) {
+			if f.err != nil {
+				cancelAll(futures)
+				return nil, f.err
+			}
+			completed++
+		}
+		if completed < len(futures) {
+			cancelAll(futures)
+			return nil, ctx.Err()
+		}
+
+		// All completed: their values can be read directly
+		vals := make([]T, len(futures))
+		for i, f := range futures {
+			vals[i] = f.val
+		}
+		return vals, nil
+	})
+}
+
+// Returns a future of the first future to SUCCEED, cancelling the others.
+// Fails only if all of them fail (with all their errors joined).
+func Any[T any](futures ...*Future[T]) *Future[T] {
+	return Async(func(ctx context.Context) (T, error) {
+		defer cancelAll(futures)
+
+		errs := make([]error, 0, len(futures))
+		for f := range firstCompleted(ctx, futures) {
+			if f.err == nil {
+				return f.val, nil
+			}
+			errs = append(errs, f.err)
+		}
+
+		var zero T
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		// `errors.Join` of no errors is nil: without this, `Any()` would "succeed" with the zero value
+		if len(futures) == 0 {
+			return zero, errors.New("any of no futures")
+		}
+		return zero, errors.Join(errs...)
+	})
+}
+
+// Returns a future of the first future to COMPLETE (success or failure), cancelling the others.
+func Race[T any](futures ...*Future[T]) *Future[T] {
+	return Async(func(ctx context.Context) (T, error) {
+		defer cancelAll(futures)
+
+		for f := range firstCompleted(ctx, futures) {
+			return f.val, f.err
+		}
+
+		var zero T
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		return zero, errors.New("race of no futures")
+	})
+}
+
+// Returns a future of `f`'s result, or failing with a `*TimeoutError` (see `43-context-timeouts` chapter)
+// if `f` takes longer than `d`.
+// On timeout, `f` is cancelled.
+func Timeout[T any](f *Future[T], d time.Duration) *Future[T] {
+	return Async(func(ctx context.Context) (T, error) {
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+
+		val, err := f.Await(ctx)
+		if err != nil && ctx.Err() != nil {
+			f.Cancel()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return val, &TimeoutError{After: d}
+			}
+		}
+		return val, err
+	})
+}
+
+// Shorthand for `Timeout(f, d).Await(context.Background())`
+func (f *Future[T]) AwaitTimeout(d time.Duration) (T, error) {
+	return Timeout(f, d).Await(context.Background())
+}
+
+// Iterates over the futures in their completion order (until `ctx` is done)
+func firstCompleted[T any](ctx context.Context, futures []*Future[T]) iter.Seq[*Future[T]] {
+	return func(yield func(*Future[T]) bool) {
+		// Buffered with 1 slot per future, so no goroutine ever blocks on its send even if we stop iterating early
+		completed := make(chan *Future[T], len(futures))
+		for _, f := range futures {
+			go func() {
+				select {
+				case <-f.done:
+					completed <- f
+				case <-ctx.Done():
+				}
+			}()
+		}
+
+		for range futures {
+			select {
+			case f := <-completed:
+				if !yield(f) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func cancelAll[T any](futures []*Future[T]) {
+	for _, f := range futures {
+		f.Cancel()
+	}
+}
+
+// Like `time.Sleep`, but returns early (with ctx's error) if `ctx` is done.
+//
+// Contrary to `time.After`, the timer is stopped when we return early,
+// so it doesn't stay around until it fires.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Simulates an external computation taking `d` to produce `res`
+func slowResult(res string, d time.Duration) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		if err := sleepContext(ctx, d); err != nil {
+			return "", err
+		}
+		return res, nil
+	}
+}
+
+func futures_main() {
+	// Same as `timeouts_main`, but each "goroutine + channel + select" becomes one call.
+	//
+	// Here, the timeout occurs first (1s < 2s), and the computation is cancelled: its goroutine
+	// returns right away instead of sleeping its remaining second to send a result nobody reads.
+	res, err := Async(slowResult("result 1", 2*time.Second)).AwaitTimeout(time.Second)
+	fmt.Println(res, err)
+
+	// Here, the result arrives first (2s < 3s)
+	res, err = Async(slowResult("result 2", 2*time.Second)).AwaitTimeout(3 * time.Second)
+	fmt.Println(res, err)
+
+	// Chaining: the length of the result, once computed
+	length := Then(Async(slowResult("chained", 100*time.Millisecond)), func(_ context.Context, s string) (int, error) {
+		return len(s), nil
+	})
+	fmt.Println(length.Await(context.Background()))
+
+	// All: waits for all results (~300ms total, not 100+200+300ms, as they run concurrently)
+	all := All(
+		Async(slowResult("a", 100*time.Millisecond)),
+		Async(slowResult("b", 300*time.Millisecond)),
+		Async(slowResult("c", 200*time.Millisecond)),
+	)
+	fmt.Println(all.Await(context.Background()))
+
+	// Any: the failing future is skipped, the fastest successful one wins
+	fastFail := Async(func(ctx context.Context) (string, error) { return "", errors.New("replica down") })
+	anyRes := Any(fastFail, Async(slowResult("replica 2", 200*time.Millisecond)), Async(slowResult("replica 3", 100*time.Millisecond)))
+	fmt.Println(anyRes.Await(context.Background()))
+
+	// Race: the first to complete wins, even if it is a failure
+	fmt.Println(Race(fastFail, Async(slowResult("too late", 100*time.Millisecond))).Await(context.Background()))
+}
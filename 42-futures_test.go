@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Like `slowResult`, but failing with `failure` after `d`
+func failAfter(failure error, d time.Duration) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		if err := sleepContext(ctx, d); err != nil {
+			return "", err
+		}
+		return "", failure
+	}
+}
+
+// Awaits `f`, failing the test if it takes more than `max` (the combinators must not wait for slower futures)
+func awaitWithin[T any](t *testing.T, f *Future[T], max time.Duration) (T, error) {
+	t.Helper()
+	start := time.Now()
+	val, err := f.Await(context.Background())
+	if took := time.Since(start); took > max {
+		t.Errorf("completed after %v, want less than %v", took, max)
+	}
+	return val, err
+}
+
+func TestAll(t *testing.T) {
+	VerifyNoLeaks(t)
+	// The values come in the given order, not in completion order
+	vals, err := awaitWithin(t, All(
+		Async(slowResult("a", 30*time.Millisecond)),
+		Async(slowResult("b", 10*time.Millisecond)),
+		Resolved("c", nil),
+	), time.Second)
+	if err != nil || !slices.Equal(vals, []string{"a", "b", "c"}) {
+		t.Errorf("All = %q, %v, want [a b c]", vals, err)
+	}
+}
+
+func TestAllFailsFast(t *testing.T) {
+	VerifyNoLeaks(t)
+	errDown := errors.New("down")
+	slow := Async(slowResult("slow", 10*time.Second))
+	_, err := awaitWithin(t, All(slow, Async(failAfter(errDown, 10*time.Millisecond))), time.Second)
+	if !errors.Is(err, errDown) {
+		t.Errorf("All = %v, want %v", err, errDown)
+	}
+	// The slow future was cancelled, instead of running for 10s for nothing
+	if _, err := awaitWithin(t, slow, time.Second); !errors.Is(err, context.Canceled) {
+		t.Errorf("slow future = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestAny(t *testing.T) {
+	VerifyNoLeaks(t)
+	val, err := awaitWithin(t, Any(
+		Resolved("", errors.New("replica down")),
+		Async(slowResult("slow", 10*time.Second)),
+		Async(slowResult("fast", 10*time.Millisecond)),
+	), time.Second)
+	if err != nil || val != "fast" {
+		t.Errorf("Any = %q, %v, want the 1st success: fast", val, err)
+	}
+
+	err1, err2 := errors.New("1 down"), errors.New("2 down")
+	if _, err := Any(Resolved("", err1), Async(failAfter(err2, 10*time.Millisecond))).Await(context.Background()); !errors.Is(err, err1) || !errors.Is(err, err2) {
+		t.Errorf("Any of failures = %v, want both errors", err)
+	}
+
+	if val, err := Any[string]().Await(context.Background()); err == nil {
+		t.Errorf("Any() = %q, want an error", val)
+	}
+}
+
+func TestRace(t *testing.T) {
+	VerifyNoLeaks(t)
+	errDown := errors.New("down")
+	// The 1st to settle wins, even a failure
+	_, err := awaitWithin(t, Race(Async(slowResult("slow", 10*time.Second)), Async(failAfter(errDown, 10*time.Millisecond))), time.Second)
+	if !errors.Is(err, errDown) {
+		t.Errorf("Race = %v, want %v", err, errDown)
+	}
+	val, err := Race(Async(slowResult("slow", 10*time.Second)), Resolved("now", nil)).Await(context.Background())
+	if err != nil || val != "now" {
+		t.Errorf("Race = %q, %v, want now", val, err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	VerifyNoLeaks(t)
+	slow := Async(slowResult("slow", 10*time.Second))
+	_, err := awaitWithin(t, Timeout(slow, 20*time.Millisecond), time.Second)
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.After != 20*time.Millisecond || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Timeout = %v, want a *TimeoutError after 20ms", err)
+	}
+	if _, err := slow.Await(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Errorf("timed out future = %v, want %v", err, context.Canceled)
+	}
+
+	if val, err := Async(slowResult("fast", 0)).AwaitTimeout(time.Second); err != nil || val != "fast" {
+		t.Errorf("AwaitTimeout = %q, %v, want fast", val, err)
+	}
+}
+
+func TestThen(t *testing.T) {
+	n, err := Then(Resolved("abc", nil), func(_ context.Context, s string) (int, error) { return len(s), nil }).Await(context.Background())
+	if err != nil || n != 3 {
+		t.Errorf("Then = %d, %v, want 3", n, err)
+	}
+	errDown := errors.New("down")
+	called := false
+	_, err = Then(Resolved("", errDown), func(context.Context, string) (int, error) { called = true; return 0, nil }).Await(context.Background())
+	if !errors.Is(err, errDown) || called {
+		t.Errorf("Then of a failure = %v (fn called: %v), want %v without calling fn", err, called, errDown)
+	}
+}
+
+func TestAsyncPanic(t *testing.T) {
+	_, err := Async(func(context.Context) (int, error) { panic("boom") }).Await(context.Background())
+	if err == nil {
+		t.Error("a panicking future succeeded")
+	}
+}
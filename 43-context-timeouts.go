@@ -0,0 +1,195 @@
+// In `30-timeouts` chapter, every timeout is hand-written: a goroutine, a buffered channel and a `select`
+// with `time.After`. Moreover:
+// - `time.After` creates a timer that is only released once it fires (here after 1s/3s), even if the result came first
+// - the computation has no way to know the caller gave up on it, so it keeps working for nothing
+//
+// The `context` package solves the 2nd issue: a `context.Context` carries a deadline (and a cancellation signal)
+// that the callee can check (`ctx.Done()`, `ctx.Deadline()`) to stop early. Contexts are passed down as the 1st
+// param of functions (by convention named `ctx`), so a deadline set at the top propagates to all callees.
+//
+// Here we wrap it all in `WithTimeout`, and build `Retry` (with exponential backoff) on top of it.
+// See `43-context-timeouts_test.go` for their tests.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Sentinel error matched (with `errors.Is`) by every timeout returned by `WithTimeout`, `Retry` and `Timeout`
+var ErrTimeout = errors.New("timeout")
+
+// Custom error type (see `23-errors` chapter) carrying how long we waited.
+//
+// - `errors.Is(err, ErrTimeout)` works thanks to the `Is` method below
+// - `errors.Is(err, context.DeadlineExceeded)` also works thanks to `Unwrap`, for code already checking the context error
+// - `errors.As(err, &te)` gives access to `After`
+type TimeoutError struct {
+	After time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timeout after %v", e.After)
+}
+
+// Called by `errors.Is` on each error of the chain, in addition to the `==` comparison
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// Runs `fn` with a context whose deadline is at most `d` from now, and returns its result,
+// or a `*TimeoutError` as soon as `d` is elapsed.
+//
+// The deadline propagates into `fn`: `fn` (and the functions it passes `ctx` to) should return early once
+// `ctx.Done()` is closed. If `ctx` already has an earlier deadline, that one is kept (a child context
+// can only shorten its parent's deadline), and its error is returned as is.
+//
+// Note: we return right away on timeout, without waiting for `fn` to actually return. Its result is sent
+// into a buffered channel (capacity 1), so `fn`'s goroutine never blocks on that send and exits once `fn` returns.
+func WithTimeout[T any](ctx context.Context, d time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	tctx, cancel := context.WithTimeout(ctx, d)
+	// Unlike `time.After`, releases the timer as soon as we return
+	defer cancel()
+
+	type result struct {
+		val T
+		err error
+	}
+	res := make(chan result, 1)
+	go func() {
+		val, err := fn(tctx)
+		res <- result{val, err}
+	}()
+
+	select {
+	case r := <-res:
+		return r.val, r.err
+	case <-tctx.Done():
+		var zero T
+		// The parent context was cancelled/expired first --> not our timeout
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		return zero, &TimeoutError{After: d}
+	}
+}
+
+// Configures how `Retry` retries
+type RetryPolicy struct {
+	// Max number of calls to `fn` (including the 1st one). 0 means retry until `ctx` is done.
+	MaxAttempts int
+	// Delay before the 1st retry, multiplied by `Multiplier` after each retry, up to `MaxDelay`.
+	// 0 means `DefaultRetryPolicy`'s: without any delay, a failing `fn` would be called in a hot loop.
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	// Fraction (between 0 and 1) of each delay that is randomized.
+	// Ex: with 0.5, a delay of 1s actually lasts between 0.5s and 1s.
+	//
+	// Without it, many clients failing at the same time would all retry at the same time too (thundering herd).
+	Jitter float64
+	// Timeout of each attempt (through `WithTimeout`). 0 means no per-attempt timeout.
+	AttemptTimeout time.Duration
+	// Decides if an error is worth retrying. nil means all errors are.
+	Retryable func(err error) bool
+}
+
+// Sensible defaults: 5 attempts, 100ms, 200ms, 400ms, 800ms between them (minus jitter)
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:  5,
+	InitialDelay: 100 * time.Millisecond,
+	MaxDelay:     5 * time.Second,
+	Multiplier:   2,
+	Jitter:       0.2,
+}
+
+// Returns the delay to wait before retry number `retry` (starting at 0)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := float64(p.InitialDelay)
+	if p.InitialDelay <= 0 {
+		delay = float64(DefaultRetryPolicy.InitialDelay)
+	}
+	for range retry {
+		delay *= max(p.Multiplier, 1)
+		if p.MaxDelay > 0 && delay >= float64(p.MaxDelay) {
+			delay = float64(p.MaxDelay)
+			break
+		}
+	}
+	// `rand.Float64` is in [0, 1)
+	delay -= delay * min(max(p.Jitter, 0), 1) * rand.Float64()
+	return time.Duration(delay)
+}
+
+// Calls `fn` until it succeeds, `policy.MaxAttempts` is reached, or `ctx` is done, waiting longer and
+// longer between attempts. Returns the last error (wrapped) if all attempts fail.
+func Retry[T any](ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	for attempt := 1; ; attempt++ {
+		var val T
+		var err error
+		if policy.AttemptTimeout > 0 {
+			val, err = WithTimeout(ctx, policy.AttemptTimeout, fn)
+		} else {
+			val, err = fn(ctx)
+		}
+		if err == nil {
+			return val, nil
+		}
+
+		if policy.Retryable != nil && !policy.Retryable(err) {
+			return zero, err
+		}
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return zero, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		// No point waiting if the context is done, we couldn't call `fn` anyway
+		if err := sleepContext(ctx, policy.backoff(attempt-1)); err != nil {
+			return zero, fmt.Errorf("retry interrupted after %d attempts: %w", attempt, err)
+		}
+	}
+}
+
+func context_timeouts_main() {
+	// Same 2 cases as `timeouts_main`, one call each
+	res, err := WithTimeout(context.Background(), time.Second, slowResult("result 1", 2*time.Second))
+	fmt.Println(res, err, errors.Is(err, ErrTimeout))
+
+	res, err = WithTimeout(context.Background(), 3*time.Second, slowResult("result 2", 2*time.Second))
+	fmt.Println(res, err)
+
+	// The callee sees the deadline set by the caller
+	deadline, _ := WithTimeout(context.Background(), 500*time.Millisecond, func(ctx context.Context) (time.Duration, error) {
+		d, _ := ctx.Deadline()
+		return time.Until(d).Round(100 * time.Millisecond), nil
+	})
+	fmt.Println("callee's remaining time:", deadline)
+
+	// A flaky computation failing twice before succeeding
+	calls := 0
+	res, err = Retry(context.Background(), DefaultRetryPolicy, func(ctx context.Context) (string, error) {
+		calls++
+		if calls < 3 {
+			return "", fmt.Errorf("attempt %d failed", calls)
+		}
+		return "succeeded", nil
+	})
+	fmt.Println(res, err, "after", calls, "calls")
+
+	// Each attempt is too slow: all 3 time out
+	policy := DefaultRetryPolicy
+	policy.MaxAttempts = 3
+	policy.AttemptTimeout = 100 * time.Millisecond
+	_, err = Retry(context.Background(), policy, slowResult("too slow", time.Second))
+	var te *TimeoutError
+	if errors.As(err, &te) {
+		fmt.Println(err, "- each attempt waited", te.After)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithTimeout(t *testing.T) {
+	VerifyNoLeaks(t)
+	start := time.Now()
+	_, err := WithTimeout(context.Background(), 20*time.Millisecond, slowResult("slow", 10*time.Second))
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("returned after %v, want right after the 20ms timeout", took)
+	}
+	var te *TimeoutError
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) || !errors.As(err, &te) || te.After != 20*time.Millisecond {
+		t.Errorf("WithTimeout = %v, want a *TimeoutError after 20ms, matching ErrTimeout and context.DeadlineExceeded", err)
+	}
+
+	if val, err := WithTimeout(context.Background(), time.Second, slowResult("fast", 0)); err != nil || val != "fast" {
+		t.Errorf("WithTimeout = %q, %v, want fast", val, err)
+	}
+}
+
+// The callee sees the deadline, and an earlier deadline of the parent is kept (with its own error)
+func TestWithTimeoutParentDeadline(t *testing.T) {
+	VerifyNoLeaks(t)
+	parent, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	// A channel, as `fn` runs in another goroutine
+	remainingCh := make(chan time.Duration, 1)
+	_, err := WithTimeout(parent, time.Hour, func(ctx context.Context) (string, error) {
+		d, _ := ctx.Deadline()
+		remainingCh <- time.Until(d)
+		return slowResult("slow", 10*time.Second)(ctx)
+	})
+	if remaining := <-remainingCh; remaining > 20*time.Millisecond {
+		t.Errorf("callee's remaining time = %v, want the parent's 20ms at most", remaining)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrTimeout) {
+		t.Errorf("WithTimeout = %v, want the parent's %v (not our timeout)", err, context.DeadlineExceeded)
+	}
+}
+
+// No jitter: each delay doubles, up to `MaxDelay`
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{InitialDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, Multiplier: 2}
+	for retry, want := range []time.Duration{10, 20, 40, 50, 50} {
+		if got := p.backoff(retry); got != want*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want %v", retry, got, want*time.Millisecond)
+		}
+	}
+	// With jitter, the delay is randomly shortened, by at most `Jitter` of it
+	p.Jitter = 0.5
+	for range 100 {
+		if got := p.backoff(1); got < 10*time.Millisecond || got > 20*time.Millisecond {
+			t.Errorf("backoff(1) with jitter = %v, want between 10ms and 20ms", got)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, Multiplier: 2}
+	errFlaky := errors.New("flaky")
+	calls := 0
+	flaky := func(failures int) func(context.Context) (int, error) {
+		calls = 0
+		return func(context.Context) (int, error) {
+			calls++
+			if calls <= failures {
+				return 0, errFlaky
+			}
+			return calls, nil
+		}
+	}
+
+	if val, err := Retry(context.Background(), policy, flaky(2)); err != nil || val != 3 {
+		t.Errorf("Retry = %d, %v, want success at the 3rd call", val, err)
+	}
+	if _, err := Retry(context.Background(), policy, flaky(5)); !errors.Is(err, errFlaky) || calls != 3 {
+		t.Errorf("Retry = %v after %d calls, want %v after 3 calls", err, calls, errFlaky)
+	}
+
+	// A non-retryable error is returned right away
+	policy.Retryable = func(err error) bool { return !errors.Is(err, errFlaky) }
+	if _, err := Retry(context.Background(), policy, flaky(5)); !errors.Is(err, errFlaky) || calls != 1 {
+		t.Errorf("Retry = %v after %d calls, want %v after 1 call", err, calls, errFlaky)
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	VerifyNoLeaks(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	// Unlimited attempts: only the context stops it, even in the middle of a (long) delay
+	_, err := Retry(ctx, RetryPolicy{InitialDelay: time.Hour}, func(context.Context) (int, error) {
+		return 0, errors.New("down")
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+		t.Errorf("Retry = %v after %v, want %v right after 50ms", err, time.Since(start), context.DeadlineExceeded)
+	}
+}
+
+// The zero policy waits between attempts, instead of calling `fn` in a hot loop
+func TestRetryZeroPolicy(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	calls := 0
+	Retry(ctx, RetryPolicy{}, func(context.Context) (int, error) {
+		calls++
+		return 0, errors.New("down")
+	})
+	if calls > 2 {
+		t.Errorf("%d calls in 50ms, want 1 (then waiting %v)", calls, DefaultRetryPolicy.InitialDelay)
+	}
+}
+
+func TestRetryAttemptTimeout(t *testing.T) {
+	VerifyNoLeaks(t)
+	policy := RetryPolicy{MaxAttempts: 2, InitialDelay: time.Millisecond, AttemptTimeout: 10 * time.Millisecond}
+	_, err := Retry(context.Background(), policy, slowResult("slow", 10*time.Second))
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.After != 10*time.Millisecond {
+		t.Errorf("Retry = %v, want a *TimeoutError after 10ms", err)
+	}
+}
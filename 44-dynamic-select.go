@@ -0,0 +1,304 @@
+// `select` (see `29-select` chapter) can only wait on a fixed set of cases written at compile time (`c1`, `c2`).
+// But sometimes the channels to wait on are only known at runtime, and change over time (ex: one channel per connected client).
+//
+// Here we build a `Multiplexer` to which channels (sources) can be added and removed at runtime, and which
+// forwards every received value into a single output channel, tagged with the name of its source.
+//
+// 2 strategies are implemented:
+// - `reflectMultiplexer`: 1 goroutine calling `reflect.Select`, which is a `select` over a slice of cases built at runtime.
+// - `mergeMultiplexer`: 1 goroutine per source, forwarding its values into the output channel ("fan-in").
+//
+// `reflect.Select` has to scan all cases on every call (O(N)) and boxes values into `reflect.Value`,
+// whereas in the merge strategy, the Go runtime only wakes up the goroutines whose source has a value.
+// See the benchmarks in `44-dynamic-select_test.go` (`go test -bench Multiplexer`).
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// A value received from a source.
+//
+// When a source gets closed, a last message with `Closed` set to true (and a zero `Value`) is sent,
+// and the source is removed from the multiplexer. Other sources are not affected.
+type Message[T any] struct {
+	Source string
+	Value  T
+	Closed bool
+}
+
+type Multiplexer[T any] interface {
+	// Starts receiving from `ch`. Replaces the source with the same name if any.
+	Add(name string, ch <-chan T)
+	// Stops receiving from the named source (the channel itself is not closed, it's not ours)
+	Remove(name string)
+	// Channel of all values received from all sources
+	Out() <-chan Message[T]
+	// Removes all sources and closes `Out()`
+	Close()
+}
+
+// 1. Strategy with `reflect.Select`
+
+// Add/Remove request sent to the select loop.
+// The loop is the only one touching the cases slice, so no mutex is needed.
+type muxCommand[T any] struct {
+	name string
+	ch   <-chan T // nil to remove
+}
+
+type reflectMultiplexer[T any] struct {
+	out  chan Message[T]
+	cmds chan muxCommand[T]
+	quit chan struct{}
+	once sync.Once
+	// Closed when the loop has exited
+	stopped chan struct{}
+}
+
+func NewReflectMultiplexer[T any]() Multiplexer[T] {
+	m := &reflectMultiplexer[T]{
+		out:     make(chan Message[T]),
+		cmds:    make(chan muxCommand[T]),
+		quit:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go m.loop()
+	return m
+}
+
+func (m *reflectMultiplexer[T]) Add(name string, ch <-chan T) {
+	select {
+	case m.cmds <- muxCommand[T]{name, ch}:
+	case <-m.stopped:
+	}
+}
+
+func (m *reflectMultiplexer[T]) Remove(name string) {
+	select {
+	case m.cmds <- muxCommand[T]{name: name}:
+	case <-m.stopped:
+	}
+}
+
+func (m *reflectMultiplexer[T]) Out() <-chan Message[T] {
+	return m.out
+}
+
+func (m *reflectMultiplexer[T]) Close() {
+	m.once.Do(func() { close(m.quit) })
+	<-m.stopped
+}
+
+func (m *reflectMultiplexer[T]) loop() {
+	// `out` first: `Close` returns once `stopped` is closed, and `Out()` must already be closed by then.
+	// (2 `defer` lines would run the other way around: last deferred, first run)
+	defer func() {
+		close(m.out)
+		close(m.stopped)
+	}()
+
+	// The 2 first cases are fixed (commands & quit), the sources come after.
+	// `names[i]` is the name of the source of `cases[i+2]`.
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(m.cmds)},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(m.quit)},
+	}
+	var names []string
+
+	remove := func(name string) {
+		for i, n := range names {
+			if n == name {
+				names = append(names[:i], names[i+1:]...)
+				cases = append(cases[:i+2], cases[i+3:]...)
+				return
+			}
+		}
+	}
+	apply := func(cmd muxCommand[T]) {
+		remove(cmd.name)
+		if cmd.ch != nil {
+			names = append(names, cmd.name)
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(cmd.ch)})
+		}
+	}
+
+	for {
+		// Same as a `select` with one `case` per element of `cases`.
+		// Returns the index of the chosen case, and for a receive, the value & the "more" boolean (`v, more := <-ch`)
+		chosen, recv, ok := reflect.Select(cases)
+		switch chosen {
+		case 0:
+			apply(recv.Interface().(muxCommand[T]))
+			continue
+		case 1:
+			return
+		}
+
+		msg := Message[T]{Source: names[chosen-2]}
+		if ok {
+			msg.Value = recv.Interface().(T)
+		} else {
+			msg.Closed = true
+			remove(msg.Source)
+		}
+
+		// While waiting for the consumer, keep handling commands (otherwise `Add`/`Remove` would block until then)
+		for delivered := false; !delivered; {
+			select {
+			case m.out <- msg:
+				delivered = true
+			case cmd := <-m.cmds:
+				apply(cmd)
+			case <-m.quit:
+				return
+			}
+		}
+	}
+}
+
+// 2. Strategy with 1 forwarding goroutine per source
+
+type mergeMultiplexer[T any] struct {
+	out chan Message[T]
+
+	mu sync.Mutex
+	// Closing a source's channel stops its forwarding goroutine
+	stops  map[string]chan struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func NewMergeMultiplexer[T any]() Multiplexer[T] {
+	return &mergeMultiplexer[T]{
+		out:   make(chan Message[T]),
+		stops: map[string]chan struct{}{},
+	}
+}
+
+func (m *mergeMultiplexer[T]) Add(name string, ch <-chan T) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+
+	m.removeLocked(name)
+	stop := make(chan struct{})
+	m.stops[name] = stop
+	m.wg.Add(1)
+	go m.forward(name, ch, stop)
+}
+
+func (m *mergeMultiplexer[T]) Remove(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeLocked(name)
+}
+
+// Must be called with `m.mu` locked
+func (m *mergeMultiplexer[T]) removeLocked(name string) {
+	if stop, ok := m.stops[name]; ok {
+		close(stop)
+		delete(m.stops, name)
+	}
+}
+
+func (m *mergeMultiplexer[T]) Out() <-chan Message[T] {
+	return m.out
+}
+
+func (m *mergeMultiplexer[T]) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	for name := range m.stops {
+		m.removeLocked(name)
+	}
+	m.mu.Unlock()
+
+	// `out` can only be closed once no goroutine can send on it anymore
+	m.wg.Wait()
+	close(m.out)
+}
+
+func (m *mergeMultiplexer[T]) forward(name string, ch <-chan T, stop chan struct{}) {
+	defer m.wg.Done()
+
+	for {
+		msg := Message[T]{Source: name}
+		select {
+		case v, ok := <-ch:
+			if ok {
+				msg.Value = v
+			} else {
+				msg.Closed = true
+			}
+		case <-stop:
+			return
+		}
+
+		select {
+		case m.out <- msg:
+		case <-stop:
+			return
+		}
+
+		if msg.Closed {
+			m.mu.Lock()
+			// Only if the source was not replaced by another channel in the meantime
+			if m.stops[name] == stop {
+				m.removeLocked(name)
+			}
+			m.mu.Unlock()
+			return
+		}
+	}
+}
+
+func dynamic_select_main() {
+	for _, newMux := range []func() Multiplexer[string]{NewReflectMultiplexer[string], NewMergeMultiplexer[string]} {
+		mux := newMux()
+		fmt.Printf("%T\n", mux)
+
+		c1 := make(chan string)
+		c2 := make(chan string)
+		mux.Add("c1", c1)
+		mux.Add("c2", c2)
+
+		go func() {
+			c1 <- "one"
+			close(c1)
+		}()
+		go func() {
+			c2 <- "two"
+			c2 <- "three"
+		}()
+
+		// 2 values + c1 closing + 1 value
+		for range 4 {
+			msg := <-mux.Out()
+			if msg.Closed {
+				fmt.Println("source", msg.Source, "closed")
+			} else {
+				fmt.Println("received from", msg.Source, msg.Value)
+			}
+		}
+
+		// A source added later on
+		c3 := make(chan string, 1)
+		mux.Add("c3", c3)
+		c3 <- "four"
+		fmt.Println("received from c3", (<-mux.Out()).Value)
+
+		mux.Close()
+		// `Out` is closed --> receive immediately succeeds with `ok` false
+		_, ok := <-mux.Out()
+		fmt.Println("out open:", ok)
+	}
+}
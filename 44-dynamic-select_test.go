@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+var multiplexerStrategies = []struct {
+	name   string
+	newMux func() Multiplexer[int]
+}{
+	{"reflect", NewReflectMultiplexer[int]},
+	{"merge", NewMergeMultiplexer[int]},
+}
+
+func TestMultiplexerCloseClosesOut(t *testing.T) {
+	for _, strategy := range multiplexerStrategies {
+		t.Run(strategy.name, func(t *testing.T) {
+			VerifyNoLeaks(t)
+			mux := strategy.newMux()
+			src := make(chan int, 1)
+			mux.Add("src", src)
+			src <- 1
+			if msg := <-mux.Out(); msg.Source != "src" || msg.Value != 1 {
+				t.Errorf("received %+v, want 1 from src", msg)
+			}
+
+			mux.Close()
+			// Already closed: not even a moment later
+			select {
+			case msg, ok := <-mux.Out():
+				if ok {
+					t.Errorf("received %+v after Close", msg)
+				}
+			default:
+				t.Error("Out() still open when Close returned")
+			}
+			// Closing twice is fine
+			mux.Close()
+		})
+	}
+}
+
+func TestMultiplexerSourceClosed(t *testing.T) {
+	for _, strategy := range multiplexerStrategies {
+		t.Run(strategy.name, func(t *testing.T) {
+			VerifyNoLeaks(t)
+			mux := strategy.newMux()
+			defer mux.Close()
+			a, b := make(chan int), make(chan int)
+			mux.Add("a", a)
+			mux.Add("b", b)
+			close(a)
+			select {
+			case msg := <-mux.Out():
+				if msg.Source != "a" || !msg.Closed {
+					t.Errorf("received %+v, want the closing of a", msg)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("closing of a not received")
+			}
+			// The other source still works
+			go func() { b <- 2 }()
+			if msg := <-mux.Out(); msg.Source != "b" || msg.Value != 2 {
+				t.Errorf("received %+v, want 2 from b", msg)
+			}
+		})
+	}
+}
+
+// 1 message going through a multiplexer of `n` sources (round-robin over the sources), for each strategy:
+//
+//	go test -bench Multiplexer
+func BenchmarkMultiplexer(b *testing.B) {
+	for _, strategy := range multiplexerStrategies {
+		for _, n := range []int{10, 100, 1000} {
+			b.Run(fmt.Sprintf("%s/%d-sources", strategy.name, n), func(b *testing.B) {
+				mux := strategy.newMux()
+				defer mux.Close()
+
+				sources := make([]chan int, n)
+				for i := range sources {
+					sources[i] = make(chan int, 1)
+					mux.Add(fmt.Sprint("source", i), sources[i])
+				}
+
+				// Read `b.N` once: the producer might still be running when the next round overwrites it
+				total := b.N
+				b.ResetTimer()
+				go func() {
+					for i := 0; i < total; i++ {
+						sources[i%n] <- i
+					}
+				}()
+				for i := 0; i < total; i++ {
+					<-mux.Out()
+				}
+			})
+		}
+	}
+}
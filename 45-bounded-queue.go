@@ -0,0 +1,260 @@
+// In `31-non-blocking-channels` chapter, a `select` with `default` lets us try a send/receive without blocking.
+// But when the send fails, the value silently falls through to `default` and is lost, and nobody knows how many were.
+//
+// When a producer is faster than its consumer, the queue between them fills up and the producer has to make
+// a decision (backpressure): wait, give up, or make room by dropping something.
+// Here we build a generic bounded `Queue[T]` making that decision explicit through an `OverflowStrategy`,
+// and counting what was dropped/rejected.
+//
+// The queue is simply a buffered channel under the hood (which is already a thread-safe bounded FIFO queue).
+// Each strategy is tested in `45-bounded-queue_test.go`.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// What `Send` does when the queue is full
+type OverflowStrategy int
+
+const (
+	// Returns `ErrQueueFull`, the value is not enqueued
+	OverflowReject OverflowStrategy = iota
+	// Removes the oldest value of the queue (the next one to be received) to make room for the new one
+	OverflowDropOldest
+	// Drops the value being sent (the newest one), the queue is left untouched
+	OverflowDropNewest
+	// Waits until there is room (same as a plain channel send)
+	OverflowBlock
+)
+
+func (s OverflowStrategy) String() string {
+	switch s {
+	case OverflowReject:
+		return "reject"
+	case OverflowDropOldest:
+		return "drop oldest"
+	case OverflowDropNewest:
+		return "drop newest"
+	case OverflowBlock:
+		return "block"
+	default:
+		return fmt.Sprintf("OverflowStrategy(%d)", int(s))
+	}
+}
+
+var ErrQueueFull = errors.New("queue full")
+var ErrQueueClosed = errors.New("queue closed")
+
+type QueueStats struct {
+	Sent, Received, Dropped, Rejected uint64
+}
+
+type Queue[T any] struct {
+	items    chan T
+	strategy OverflowStrategy
+
+	// Closed by `Close`. We never close `items` itself, as a concurrent send would then panic.
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	// Atomic counters (see `39-atomic-counters` chapter), as the queue is used by several goroutines at once
+	sent, received, dropped, rejected atomic.Uint64
+}
+
+// Panics if `capacity` is less than 1: a queue of 0 would be an unbuffered channel, which is never "not full"
+// without a receiver waiting (`Send` with the drop-oldest strategy would spin forever), and `make` panics on
+// a negative size anyway. Like an index out of range, that's a programming error, not a runtime condition.
+func NewQueue[T any](capacity int, strategy OverflowStrategy) *Queue[T] {
+	if capacity < 1 {
+		panic(fmt.Sprintf("bounded queue capacity must be at least 1, got %d", capacity))
+	}
+	return &Queue[T]{
+		items:    make(chan T, capacity),
+		strategy: strategy,
+		closed:   make(chan struct{}),
+	}
+}
+
+func (q *Queue[T]) isClosed() bool {
+	select {
+	case <-q.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Enqueues `v`, applying the queue's `OverflowStrategy` if the queue is full.
+//
+// Returns `ErrQueueFull` (reject strategy) or `ErrQueueClosed`. A value dropped by the
+// drop strategies is not an error, it's only counted in `Stats().Dropped`.
+func (q *Queue[T]) Send(v T) error {
+	if q.isClosed() {
+		return ErrQueueClosed
+	}
+
+	switch q.strategy {
+	case OverflowBlock:
+		select {
+		case q.items <- v:
+		case <-q.closed:
+			return ErrQueueClosed
+		}
+
+	case OverflowDropOldest:
+		// Other goroutines might be sending/receiving at the same time, so we loop until our send succeeds
+		for !q.TrySend(v) {
+			if q.isClosed() {
+				return ErrQueueClosed
+			}
+			select {
+			case <-q.items:
+				q.dropped.Add(1)
+			default:
+				// Emptied by a receiver in the meantime, try sending again
+			}
+		}
+		return nil
+
+	case OverflowDropNewest:
+		if !q.TrySend(v) {
+			q.dropped.Add(1)
+		}
+		return nil
+
+	default:
+		if !q.TrySend(v) {
+			q.rejected.Add(1)
+			return ErrQueueFull
+		}
+		return nil
+	}
+
+	q.sent.Add(1)
+	return nil
+}
+
+// Enqueues `v` if there is room, without blocking, whatever the queue's strategy.
+// Returns whether `v` was enqueued.
+func (q *Queue[T]) TrySend(v T) bool {
+	if q.isClosed() {
+		return false
+	}
+	select {
+	case q.items <- v:
+		q.sent.Add(1)
+		return true
+	default:
+		return false
+	}
+}
+
+// Waits at most `d` for room in the queue, whatever the queue's strategy.
+// Returns a `*TimeoutError` (see `43-context-timeouts` chapter) if there was none.
+func (q *Queue[T]) SendTimeout(v T, d time.Duration) error {
+	if q.isClosed() {
+		return ErrQueueClosed
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case q.items <- v:
+		q.sent.Add(1)
+		return nil
+	case <-q.closed:
+		return ErrQueueClosed
+	case <-t.C:
+		q.rejected.Add(1)
+		return &TimeoutError{After: d}
+	}
+}
+
+// Dequeues the oldest value, waiting for one if the queue is empty.
+// `ok` is false once the queue is closed AND empty (like `v, ok := <-ch` on a closed channel).
+func (q *Queue[T]) Recv() (v T, ok bool) {
+	select {
+	case v = <-q.items:
+		q.received.Add(1)
+		return v, true
+	case <-q.closed:
+		// Values still in the queue are delivered even once closed
+		return q.TryRecv()
+	}
+}
+
+// Dequeues the oldest value if any, without blocking
+func (q *Queue[T]) TryRecv() (v T, ok bool) {
+	select {
+	case v = <-q.items:
+		q.received.Add(1)
+		return v, true
+	default:
+		return v, false
+	}
+}
+
+// Number of values currently in the queue
+func (q *Queue[T]) Len() int {
+	return len(q.items)
+}
+
+// Stops accepting values. Values already in the queue can still be received.
+func (q *Queue[T]) Close() {
+	q.closeOnce.Do(func() { close(q.closed) })
+}
+
+func (q *Queue[T]) Stats() QueueStats {
+	return QueueStats{
+		Sent:     q.sent.Load(),
+		Received: q.received.Load(),
+		Dropped:  q.dropped.Load(),
+		Rejected: q.rejected.Load(),
+	}
+}
+
+func bounded_queue_main() {
+	// Non-blocking ops, like in `non_blocking_channels_main`, but telling us what happened
+	q := NewQueue[string](1, OverflowReject)
+	if _, ok := q.TryRecv(); !ok {
+		fmt.Println("no message received")
+	}
+	fmt.Println("sent:", q.TrySend("hi"), q.TrySend("hi again"))
+	fmt.Println(q.SendTimeout("hi once more", 100*time.Millisecond))
+
+	// A producer sending 10 values every 10ms, to a consumer taking 35ms per value, through a queue of 3
+	for _, strategy := range []OverflowStrategy{OverflowReject, OverflowDropOldest, OverflowDropNewest, OverflowBlock} {
+		q := NewQueue[int](3, strategy)
+		var received []int
+		done := make(chan bool)
+
+		go func() {
+			for {
+				v, ok := q.Recv()
+				if !ok {
+					done <- true
+					return
+				}
+				received = append(received, v)
+				time.Sleep(35 * time.Millisecond)
+			}
+		}()
+
+		for i := 1; i <= 10; i++ {
+			if err := q.Send(i); err != nil {
+				fmt.Println("send", i, "failed:", err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		q.Close()
+		<-done
+
+		fmt.Printf("%s: received %v, stats %+v\n", strategy, received, q.Stats())
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Receives everything left in the queue, without blocking
+func drainQueue[T any](q *Queue[T]) []T {
+	var vals []T
+	for {
+		v, ok := q.TryRecv()
+		if !ok {
+			return vals
+		}
+		vals = append(vals, v)
+	}
+}
+
+// Sends 1, 2, 3 into a queue of 2 without any receiver: the 3rd send overflows
+func TestQueueOverflowStrategies(t *testing.T) {
+	for _, tc := range []struct {
+		strategy OverflowStrategy
+		errs     []error
+		received []int
+		stats    QueueStats
+	}{
+		{OverflowReject, []error{nil, nil, ErrQueueFull}, []int{1, 2}, QueueStats{Sent: 2, Received: 2, Rejected: 1}},
+		{OverflowDropNewest, []error{nil, nil, nil}, []int{1, 2}, QueueStats{Sent: 2, Received: 2, Dropped: 1}},
+		{OverflowDropOldest, []error{nil, nil, nil}, []int{2, 3}, QueueStats{Sent: 3, Received: 2, Dropped: 1}},
+	} {
+		t.Run(tc.strategy.String(), func(t *testing.T) {
+			q := NewQueue[int](2, tc.strategy)
+			for i, want := range tc.errs {
+				if err := q.Send(i + 1); !errors.Is(err, want) {
+					t.Errorf("Send(%d) = %v, want %v", i+1, err, want)
+				}
+			}
+			if got := drainQueue(q); !slices.Equal(got, tc.received) {
+				t.Errorf("received %v, want %v", got, tc.received)
+			}
+			if got := q.Stats(); got != tc.stats {
+				t.Errorf("Stats() = %+v, want %+v", got, tc.stats)
+			}
+		})
+	}
+}
+
+func TestQueueBlock(t *testing.T) {
+	VerifyNoLeaks(t)
+	q := NewQueue[int](1, OverflowBlock)
+	q.Send(1)
+	sent := make(chan error)
+	go func() { sent <- q.Send(2) }()
+
+	select {
+	case err := <-sent:
+		t.Fatalf("Send into a full queue returned %v, want it to block", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// Room made: the blocked send goes through
+	if v, _ := q.Recv(); v != 1 {
+		t.Errorf("Recv() = %d, want 1", v)
+	}
+	if err := <-sent; err != nil {
+		t.Errorf("Send = %v once there is room", err)
+	}
+	if v, _ := q.Recv(); v != 2 {
+		t.Errorf("Recv() = %d, want 2", v)
+	}
+	if got, want := q.Stats(), (QueueStats{Sent: 2, Received: 2}); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestQueueCloseUnblocks(t *testing.T) {
+	VerifyNoLeaks(t)
+	full := NewQueue[int](1, OverflowBlock)
+	full.Send(1)
+	empty := NewQueue[int](1, OverflowBlock)
+	sent := make(chan error)
+	received := make(chan bool)
+	go func() { sent <- full.Send(2) }()
+	go func() {
+		_, ok := empty.Recv()
+		received <- ok
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	full.Close()
+	empty.Close()
+	if err := <-sent; !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("blocked Send after Close = %v, want %v", err, ErrQueueClosed)
+	}
+	if ok := <-received; ok {
+		t.Error("blocked Recv after Close received a value from an empty queue")
+	}
+
+	// Values already queued are still delivered, then `ok` is false
+	if v, ok := full.Recv(); !ok || v != 1 {
+		t.Errorf("Recv() after Close = %d, %v, want 1, true", v, ok)
+	}
+	if _, ok := full.Recv(); ok {
+		t.Error("Recv() on a closed & empty queue: ok = true")
+	}
+	for _, strategy := range []OverflowStrategy{OverflowReject, OverflowDropOldest, OverflowDropNewest, OverflowBlock} {
+		q := NewQueue[int](1, strategy)
+		q.Close()
+		if err := q.Send(1); !errors.Is(err, ErrQueueClosed) {
+			t.Errorf("%v: Send after Close = %v, want %v", strategy, err, ErrQueueClosed)
+		}
+	}
+}
+
+func TestQueueSendTimeout(t *testing.T) {
+	q := NewQueue[int](1, OverflowDropOldest)
+	if err := q.SendTimeout(1, time.Second); err != nil {
+		t.Errorf("SendTimeout into an empty queue = %v", err)
+	}
+	// Whatever the strategy, nothing is dropped: it waits, then gives up
+	err := q.SendTimeout(2, 10*time.Millisecond)
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.After != 10*time.Millisecond {
+		t.Errorf("SendTimeout into a full queue = %v, want a *TimeoutError after 10ms", err)
+	}
+	if got, want := q.Stats(), (QueueStats{Sent: 1, Rejected: 1}); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNewQueueInvalidCapacity(t *testing.T) {
+	for _, capacity := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewQueue(%d) didn't panic", capacity)
+				}
+			}()
+			NewQueue[int](capacity, OverflowDropOldest)
+		}()
+	}
+}
@@ -0,0 +1,343 @@
+// The previous chapters covered `sync.WaitGroup`, `sync.Mutex` and `atomic.Uint64`.
+// Here we build a few other classic synchronization primitives on top of them (and channels):
+//
+//   - `Semaphore`: limits how many "units" of a resource can be used at once (a mutex is a semaphore of 1).
+//     Weighted: a goroutine can acquire several units at once (ex: a big job taking 3 of the 10 available slots).
+//   - `CountDownLatch`: lets goroutines wait until N events happened (like a `WaitGroup` whose counter is
+//     only set once at creation, and that can be awaited with a timeout).
+//   - `Barrier`: lets N goroutines wait for each other at a meeting point, before all moving on together.
+//     Cyclic: it can be reused for the next meeting point (ex: phase 1, then phase 2, etc.).
+//   - `Lazy[T]`: computes a value once, on first use (like `sync.Once`), except that if the computation fails,
+//     the next call retries it instead of caching the error forever.
+//
+// They are stress-tested in `46-sync-primitives_test.go`: run `go test -race -run Stress` to also check for data races.
+package main
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 1. Weighted semaphore
+
+// A goroutine waiting in `Acquire`
+type semaphoreWaiter struct {
+	n int64
+	// Closed once the `n` units are granted
+	ready chan struct{}
+}
+
+type Semaphore struct {
+	mu   sync.Mutex
+	size int64
+	used int64
+	// Queue of `semaphoreWaiter`, in arrival order
+	waiters list.List
+}
+
+func NewSemaphore(size int64) *Semaphore {
+	return &Semaphore{size: size}
+}
+
+// Blocks until `n` units are available (or `ctx` is done, in which case nothing is acquired).
+//
+// Waiters are served in FIFO order: a big request waiting at the front of the queue is not overtaken by
+// smaller ones arriving later (even if there would be room for them). Otherwise a stream of small requests
+// could keep a big one waiting forever (starvation).
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	s.mu.Lock()
+	if s.size-s.used >= n && s.waiters.Len() == 0 {
+		s.used += n
+		s.mu.Unlock()
+		return nil
+	}
+
+	if n > s.size {
+		// Can never be satisfied, no point queuing
+		s.mu.Unlock()
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	w := semaphoreWaiter{n: n, ready: make(chan struct{})}
+	elem := s.waiters.PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-w.ready:
+			// Granted right when `ctx` was done: we own the units now, so let's just consider it a success
+			return nil
+		default:
+		}
+
+		isFront := s.waiters.Front() == elem
+		s.waiters.Remove(elem)
+		// We were blocking the ones behind us, they might fit now
+		if isFront {
+			s.notifyWaiters()
+		}
+		return ctx.Err()
+	}
+}
+
+// Acquires `n` units if available right now, without blocking. Returns whether it did.
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size-s.used >= n && s.waiters.Len() == 0 {
+		s.used += n
+		return true
+	}
+	return false
+}
+
+// Gives back `n` units (acquired before)
+func (s *Semaphore) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.used -= n
+	if s.used < 0 {
+		panic("semaphore: released more than held")
+	}
+	s.notifyWaiters()
+}
+
+// Grants units to the waiters at the front of the queue, as long as they fit.
+// Must be called with `s.mu` locked.
+func (s *Semaphore) notifyWaiters() {
+	for {
+		front := s.waiters.Front()
+		if front == nil {
+			return
+		}
+		w := front.Value.(semaphoreWaiter)
+		if s.size-s.used < w.n {
+			// Not enough room for the 1st one: stop there (FIFO), even if the next ones would fit
+			return
+		}
+		s.used += w.n
+		s.waiters.Remove(front)
+		close(w.ready)
+	}
+}
+
+// 2. Count-down latch
+
+type CountDownLatch struct {
+	mu    sync.Mutex
+	count int
+	// Closed when `count` reaches 0
+	done chan struct{}
+}
+
+func NewCountDownLatch(count int) *CountDownLatch {
+	l := &CountDownLatch{count: count, done: make(chan struct{})}
+	if count <= 0 {
+		close(l.done)
+	}
+	return l
+}
+
+// Decrements the counter, releasing all waiters when it reaches 0. Extra calls are ignored.
+func (l *CountDownLatch) CountDown() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.count == 0 {
+		return
+	}
+	l.count--
+	if l.count == 0 {
+		close(l.done)
+	}
+}
+
+func (l *CountDownLatch) Count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.count
+}
+
+// Blocks until the counter reaches 0 (or `ctx` is done)
+func (l *CountDownLatch) Await(ctx context.Context) error {
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// 3. Cyclic barrier
+
+type Barrier struct {
+	mu      sync.Mutex
+	parties int
+	arrived int
+	phase   int
+	// Closed when all parties of the current phase have arrived, then replaced by a new one for the next phase.
+	// That's what makes the barrier reusable: goroutines of the next phase wait on a fresh channel.
+	release chan struct{}
+	// Optional, run by the last goroutine to arrive, before releasing the others
+	action func(phase int)
+}
+
+func NewBarrier(parties int, action func(phase int)) *Barrier {
+	return &Barrier{parties: parties, release: make(chan struct{}), action: action}
+}
+
+// Blocks until `parties` goroutines have called `Await` (for this phase), and returns the phase number (starting at 0)
+func (b *Barrier) Await() int {
+	b.mu.Lock()
+	phase, release := b.phase, b.release
+	b.arrived++
+
+	if b.arrived < b.parties {
+		b.mu.Unlock()
+		<-release
+		return phase
+	}
+
+	// Last one to arrive: start the next phase & wake up everyone
+	if b.action != nil {
+		b.action(phase)
+	}
+	b.arrived = 0
+	b.phase++
+	b.release = make(chan struct{})
+	b.mu.Unlock()
+
+	close(release)
+	return phase
+}
+
+// 4. Lazy value
+
+type Lazy[T any] struct {
+	fn   func() (T, error)
+	mu   sync.Mutex
+	done atomic.Bool
+	val  T
+}
+
+func NewLazy[T any](fn func() (T, error)) *Lazy[T] {
+	return &Lazy[T]{fn: fn}
+}
+
+// Returns the value, computing it if it wasn't successfully computed yet.
+// Concurrent callers wait for the same computation (it never runs twice at the same time).
+func (l *Lazy[T]) Get() (T, error) {
+	// Fast path once computed: an atomic load, no locking
+	if l.done.Load() {
+		return l.val, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Someone else might have computed it while we were waiting for the lock
+	if l.done.Load() {
+		return l.val, nil
+	}
+
+	val, err := l.fn()
+	if err != nil {
+		// Not cached: the next `Get` will try again
+		return val, err
+	}
+	l.val = val
+	l.done.Store(true)
+	return val, nil
+}
+
+func sync_primitives_main() {
+	// Semaphore: at most 4 units of "memory" used at once, big jobs taking 3 of them
+	sem := NewSemaphore(4)
+	var inUse, maxInUse atomic.Int64
+	var wg sync.WaitGroup
+	for id := 1; id <= 6; id++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			weight := int64(1)
+			if id%3 == 0 {
+				weight = 3
+			}
+			if err := sem.Acquire(context.Background(), weight); err != nil {
+				return
+			}
+			defer sem.Release(weight)
+
+			cur := inUse.Add(weight)
+			// Atomic "max": retry if another goroutine updated `maxInUse` between our load and our swap
+			for {
+				old := maxInUse.Load()
+				if cur <= old || maxInUse.CompareAndSwap(old, cur) {
+					break
+				}
+			}
+			fmt.Println("job", id, "running with weight", weight)
+			time.Sleep(100 * time.Millisecond)
+			inUse.Add(-weight)
+		}()
+	}
+	wg.Wait()
+	fmt.Println("max units in use:", maxInUse.Load())
+
+	// Does not wait
+	fmt.Println("try acquire 5:", sem.TryAcquire(5))
+	// Gives up after 50ms
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	fmt.Println("acquire 5:", sem.Acquire(ctx, 5))
+	cancel()
+
+	// Latch: wait for 3 services to be started
+	ready := NewCountDownLatch(3)
+	for _, service := range []string{"db", "cache", "api"} {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			fmt.Println(service, "started")
+			ready.CountDown()
+		}()
+	}
+	_ = ready.Await(context.Background())
+	fmt.Println("all services started")
+
+	// Barrier: 3 workers go through 3 phases, no worker starts a phase before all finished the previous one
+	barrier := NewBarrier(3, func(phase int) { fmt.Println("--- phase", phase, "done") })
+	for w := 1; w <= 3; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for phase := range 3 {
+				time.Sleep(time.Duration(w*10) * time.Millisecond)
+				fmt.Println("worker", w, "finished phase", phase)
+				barrier.Await()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Lazy: the 1st load fails, the 2nd is retried & succeeds, the 3rd is cached
+	attempts := 0
+	config := NewLazy(func() (map[string]string, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New("config server unreachable")
+		}
+		return map[string]string{"env": "prod"}, nil
+	})
+	for range 3 {
+		fmt.Println(config.Get())
+	}
+	fmt.Println("loaded in", attempts, "attempts")
+}
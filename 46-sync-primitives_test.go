@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const stressGoroutines, stressIterations = 100, 200
+
+// Lots of goroutines acquiring & releasing different weights, some of them timing out
+func TestSemaphoreStress(t *testing.T) {
+	VerifyNoLeaks(t)
+	sem := NewSemaphore(10)
+	var inUse atomic.Int64
+	var wg sync.WaitGroup
+	for g := range stressGoroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := int64(g%3 + 1)
+			for range stressIterations {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+				// Some acquisitions time out: checks cancelled waiters don't leak units
+				if sem.Acquire(ctx, n) == nil {
+					if used := inUse.Add(n); used > 10 {
+						t.Errorf("semaphore over capacity: %d units in use", used)
+					}
+					inUse.Add(-n)
+					sem.Release(n)
+				}
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !sem.TryAcquire(10) {
+		t.Error("semaphore units leaked: can't acquire all of them once everyone released")
+	}
+}
+
+// Goroutines going through the barrier's phases together, then counting the latch down & getting the lazy value
+func TestBarrierLatchLazyStress(t *testing.T) {
+	VerifyNoLeaks(t)
+	latch := NewCountDownLatch(stressGoroutines)
+	barrier := NewBarrier(stressGoroutines, nil)
+	var lazyCalls atomic.Int64
+	lazy := NewLazy(func() (int, error) {
+		if lazyCalls.Add(1) < 5 {
+			return 0, errors.New("not yet")
+		}
+		return 42, nil
+	})
+
+	var phases [stressIterations]atomic.Int64
+	var wg sync.WaitGroup
+	for range stressGoroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range stressIterations {
+				// Everyone must have reached phase `i` before anyone reaches phase `i+1`
+				phases[i].Add(1)
+				if p := barrier.Await(); p != i {
+					t.Errorf("barrier phase = %d, want %d", p, i)
+				}
+				if n := phases[i].Load(); n != stressGoroutines {
+					t.Errorf("barrier released phase %d with %d goroutines, want %d", i, n, stressGoroutines)
+				}
+			}
+			latch.CountDown()
+			if v, err := lazy.Get(); err == nil && v != 42 {
+				t.Errorf("lazy value = %d, want 42", v)
+			}
+		}()
+	}
+
+	if err := latch.Await(context.Background()); err != nil || latch.Count() != 0 {
+		t.Errorf("latch released with count %d (err: %v)", latch.Count(), err)
+	}
+	wg.Wait()
+
+	// Once computed, the value is cached: no more calls
+	calls := lazyCalls.Load()
+	if v, err := lazy.Get(); v != 42 || err != nil || lazyCalls.Load() != calls {
+		t.Errorf("lazy.Get() = %d, %v after %d calls, want 42 with no new call", v, err, calls)
+	}
+}
@@ -0,0 +1,183 @@
+// In `40-mutexes` chapter, several goroutines hammer the same `"a"` counter. With a mutex, every call still
+// does the work, one after the other. When the work is an expensive read (a DB query, an HTTP call, etc.) and
+// many goroutines ask for the same key at the same time, they could all share a single execution instead.
+//
+// That's "singleflight" (request deduplication): while a call for a key is in flight, other calls for the
+// same key don't start a new execution, they wait for the one in flight and get its result (and error).
+// Once it's done, the next call for that key starts a new execution (nothing is cached, unlike `Lazy`).
+// See `47-singleflight_test.go` for its tests.
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Result of a call, as sent by `DoChan`
+type FlightResult[V any] struct {
+	Val V
+	Err error
+	// Whether the result was given to several callers
+	Shared bool
+}
+
+// An execution in flight (or done) for a key
+type flightCall[V any] struct {
+	// Closed once `val` & `err` are set
+	done chan struct{}
+	val  V
+	err  error
+	// Number of callers which joined the call after it started
+	dups int
+	// Channels of the `DoChan` callers
+	chans []chan<- FlightResult[V]
+}
+
+// Zero value is ready to use (like `sync.Mutex`)
+type Group[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*flightCall[V]
+}
+
+// Executes `fn` and returns its result, unless a call for the same key is already in flight,
+// in which case it waits for that one and returns its result instead (with `shared` set to true).
+func (g *Group[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[K]*flightCall[V]{}
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		<-c.done
+		return c.val, c.err, true
+	}
+
+	c := &flightCall[V]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	g.doCall(c, key, fn)
+	return c.val, c.err, c.dups > 0
+}
+
+// Same as `Do`, but returns right away a channel receiving the result once ready.
+// Allows to stop waiting (ex: a `select` with a timeout) without cancelling the call for the other callers.
+//
+// The channel is buffered, so the execution never blocks sending to a caller that stopped listening.
+func (g *Group[K, V]) DoChan(key K, fn func() (V, error)) <-chan FlightResult[V] {
+	ch := make(chan FlightResult[V], 1)
+
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[K]*flightCall[V]{}
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		c.chans = append(c.chans, ch)
+		g.mu.Unlock()
+		return ch
+	}
+
+	c := &flightCall[V]{done: make(chan struct{}), chans: []chan<- FlightResult[V]{ch}}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	go g.doCall(c, key, fn)
+	return ch
+}
+
+// Makes the next call for `key` start a new execution, instead of joining the one in flight
+// (ex: if we know its result will be outdated). Callers already waiting still get its result.
+func (g *Group[K, V]) Forget(key K) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.calls, key)
+}
+
+func (g *Group[K, V]) doCall(c *flightCall[V], key K, fn func() (V, error)) {
+	defer func() {
+		// A panic would leave all the waiting callers blocked forever, so it's turned into an error
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("singleflight call panicked: %v", r)
+		}
+
+		g.mu.Lock()
+		// The key might have been forgotten (and even started again) in the meantime
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		// Read under the lock, as callers joining the call update them under the lock as well
+		shared, chans := c.dups > 0, c.chans
+		g.mu.Unlock()
+
+		close(c.done)
+		for _, ch := range chans {
+			ch <- FlightResult[V]{Val: c.val, Err: c.err, Shared: shared}
+		}
+	}()
+
+	c.val, c.err = fn()
+}
+
+func singleflight_main() {
+	var group Group[string, int]
+	var executions atomic.Int32
+
+	// Simulates an expensive lookup of a counter
+	lookup := func(name string) func() (int, error) {
+		return func() (int, error) {
+			executions.Add(1)
+			time.Sleep(100 * time.Millisecond)
+			return len(name) * 1000, nil
+		}
+	}
+
+	// Like `doIncrement` in `40-mutexes` chapter: lots of goroutines asking for the same `"a"` key at once
+	var wg sync.WaitGroup
+	var sharedCount atomic.Int32
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, shared := group.Do("a", lookup("a")); shared {
+				sharedCount.Add(1)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		group.Do("b", lookup("b"))
+	}()
+	wg.Wait()
+	// 2 executions (1 per key) for 11 calls
+	fmt.Println("executions:", executions.Load(), "- shared results:", sharedCount.Load())
+
+	// With `DoChan`, a caller can give up waiting without affecting the others
+	slow := func() (int, error) {
+		time.Sleep(300 * time.Millisecond)
+		return 42, nil
+	}
+	impatient := group.DoChan("slow", slow)
+	patient := group.DoChan("slow", slow)
+
+	timeout := time.NewTimer(100 * time.Millisecond)
+	defer timeout.Stop()
+	select {
+	case res := <-impatient:
+		fmt.Println("impatient got", res.Val)
+	case <-timeout.C:
+		fmt.Println("impatient timed out")
+	}
+	res := <-patient
+	fmt.Println("patient got", res.Val, "shared:", res.Shared)
+
+	// `Forget` makes the next call start a new execution even though one is in flight
+	first := group.DoChan("c", lookup("c"))
+	group.Forget("c")
+	second := group.DoChan("c", lookup("c"))
+	fmt.Println((<-first).Shared, (<-second).Shared)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Waits until `n` callers joined the call in flight for `key`
+func waitForDups[K comparable, V any](t *testing.T, g *Group[K, V], key K, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		g.mu.Lock()
+		c, ok := g.calls[key]
+		joined := ok && c.dups >= n
+		g.mu.Unlock()
+		if joined {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d callers didn't join the call for %v", n, key)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGroupDoShared(t *testing.T) {
+	VerifyNoLeaks(t)
+	var g Group[string, int]
+	var executions atomic.Int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		executions.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	const callers = 10
+	type result struct {
+		v      int
+		err    error
+		shared bool
+	}
+	results := make(chan result, callers)
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, shared := g.Do("a", fn)
+			results <- result{v, err, shared}
+		}()
+	}
+	// Only released once all the callers are waiting: they all share the 1st one's execution
+	waitForDups(t, &g, "a", callers-1)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if n := executions.Load(); n != 1 {
+		t.Errorf("fn executed %d times, want 1", n)
+	}
+	for r := range results {
+		if r.v != 42 || r.err != nil || !r.shared {
+			t.Errorf("Do = %d, %v, %v, want 42, nil, true", r.v, r.err, r.shared)
+		}
+	}
+
+	// The call is done: the next one executes `fn` again, and shares with nobody
+	if v, _, shared := g.Do("a", fn); v != 42 || shared || executions.Load() != 2 {
+		t.Errorf("Do after the call = %d, shared %v, %d executions, want 42, false, 2", v, shared, executions.Load())
+	}
+}
+
+func TestGroupDoChan(t *testing.T) {
+	VerifyNoLeaks(t)
+	var g Group[string, string]
+	release := make(chan struct{})
+	fn := func() (string, error) {
+		<-release
+		return "done", nil
+	}
+	first := g.DoChan("k", fn)
+	second := g.DoChan("k", fn)
+	// Different keys don't share
+	other := g.DoChan("other", func() (string, error) { return "other", nil })
+	if res := <-other; res.Val != "other" || res.Shared {
+		t.Errorf("other key = %+v, want other, not shared", res)
+	}
+
+	select {
+	case res := <-first:
+		t.Fatalf("received %+v before the call was done", res)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	for _, ch := range []<-chan FlightResult[string]{first, second} {
+		if res := <-ch; res.Val != "done" || res.Err != nil || !res.Shared {
+			t.Errorf("DoChan = %+v, want done, shared", res)
+		}
+	}
+}
+
+func TestGroupForget(t *testing.T) {
+	VerifyNoLeaks(t)
+	var g Group[string, int]
+	var executions atomic.Int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		return int(executions.Add(1)), nil
+	}
+	first := g.DoChan("k", fn)
+	g.Forget("k")
+	// Not joining the forgotten call: a new execution
+	second := g.DoChan("k", fn)
+	close(release)
+	r1, r2 := <-first, <-second
+	if r1.Shared || r2.Shared || r1.Val == r2.Val || executions.Load() != 2 {
+		t.Errorf("after Forget: %+v and %+v (%d executions), want 2 separate executions", r1, r2, executions.Load())
+	}
+}
+
+func TestGroupPanic(t *testing.T) {
+	VerifyNoLeaks(t)
+	var g Group[string, int]
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		panic("boom")
+	}
+	waiter := g.DoChan("k", fn)
+	errs := make(chan error, 1)
+	go func() {
+		_, err, _ := g.Do("k", fn)
+		errs <- err
+	}()
+	waitForDups(t, &g, "k", 1)
+	close(release)
+
+	// Every waiter gets the panic as an error, instead of waiting forever (or crashing the program)
+	for _, err := range []error{(<-waiter).Err, <-errs} {
+		if err == nil || !strings.Contains(err.Error(), "boom") {
+			t.Errorf("err = %v, want the panic as an error", err)
+		}
+	}
+
+	// And an error is shared like a value
+	errDown := errors.New("down")
+	if _, err, _ := g.Do("k", func() (int, error) { return 0, errDown }); !errors.Is(err, errDown) {
+		t.Errorf("Do = %v, want %v", err, errDown)
+	}
+}
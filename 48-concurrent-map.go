@@ -0,0 +1,249 @@
+// `Container` in `40-mutexes` chapter wraps a map with a single mutex: every goroutine, whatever the key it updates,
+// waits for the same lock (contention). The standard library's `sync.Map` avoids that, but it stores `any` keys & values,
+// losing type safety (type assertions everywhere), and is only optimized for specific use cases
+// (keys written once & read many times, or goroutines working on disjoint sets of keys).
+//
+// A common middle ground is a sharded map: the keys are split (by hash) over N small maps (shards), each with its own lock.
+// 2 goroutines only contend when they touch keys of the same shard.
+// See the benchmarks against `Container` & `sync.Map` in `48-concurrent-map_test.go` (`go test -bench Counters`).
+package main
+
+import (
+	"fmt"
+	"hash/maphash"
+	"iter"
+	"math"
+	"reflect"
+	"sync"
+)
+
+type mapShard[K comparable, V any] struct {
+	// Readers don't block each other with a `RWMutex`, only writers do
+	mu sync.RWMutex
+	m  map[K]V
+}
+
+type ConcurrentMap[K comparable, V any] struct {
+	shards []*mapShard[K, V]
+	hash   func(K) uint64
+}
+
+// Creates a map split over `shardCount` shards, `hash` deciding which shard a key goes to.
+// If `hash` is nil, `defaultHash` is used.
+func NewConcurrentMap[K comparable, V any](shardCount int, hash func(K) uint64) *ConcurrentMap[K, V] {
+	if shardCount < 1 {
+		shardCount = 1
+	}
+	if hash == nil {
+		hash = defaultHash[K](maphash.MakeSeed())
+	}
+
+	cm := &ConcurrentMap[K, V]{shards: make([]*mapShard[K, V], shardCount), hash: hash}
+	for i := range cm.shards {
+		cm.shards[i] = &mapShard[K, V]{m: map[K]V{}}
+	}
+	return cm
+}
+
+// Returns a hash function for any comparable type.
+//
+// Strings and integers (the most common keys) are hashed directly, other types through reflection (see `hashValue`).
+// Fyi, hashing their `fmt` representation instead would be wrong: `0.0 == -0.0` but they print differently,
+// so 2 equal keys could land in different shards (and be stored twice).
+func defaultHash[K comparable](seed maphash.Seed) func(K) uint64 {
+	return func(key K) uint64 {
+		// A type switch (see `18-interfaces` chapter) needs an interface value, hence `any(key)`
+		switch k := any(key).(type) {
+		case string:
+			return maphash.String(seed, k)
+		case int:
+			return mixBits(uint64(k))
+		case int64:
+			return mixBits(uint64(k))
+		case uint64:
+			return mixBits(k)
+		default:
+			return hashValue(seed, reflect.ValueOf(k))
+		}
+	}
+}
+
+// Hashes a comparable value consistently with `==`: equal values always have the same hash.
+// Structs & arrays combine the hashes of their fields/elements, interfaces hash their dynamic value.
+func hashValue(seed maphash.Seed, v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.Invalid:
+		// nil interface
+		return 0
+	case reflect.String:
+		return maphash.String(seed, v.String())
+	case reflect.Bool:
+		if v.Bool() {
+			return 1
+		}
+		return 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return mixBits(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return mixBits(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return hashFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return hashFloat(real(c))*31 + hashFloat(imag(c))
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		return mixBits(uint64(v.Pointer()))
+	case reflect.Interface:
+		return hashValue(seed, v.Elem())
+	case reflect.Array:
+		var h uint64
+		for i := range v.Len() {
+			h = h*31 + hashValue(seed, v.Index(i))
+		}
+		return mixBits(h)
+	case reflect.Struct:
+		var h uint64
+		for i := range v.NumField() {
+			h = h*31 + hashValue(seed, v.Field(i))
+		}
+		return mixBits(h)
+	default:
+		// Slices, maps & funcs aren't comparable: they can't be keys (in an interface, the map panics anyway)
+		panic(fmt.Sprintf("unhashable type %v", v.Type()))
+	}
+}
+
+// `-0.0 == 0.0`: both get the hash of 0 (NaN is never equal to anything, any hash will do)
+func hashFloat(f float64) uint64 {
+	if f == 0 {
+		return 0
+	}
+	return mixBits(math.Float64bits(f))
+}
+
+// Scrambles the bits of an integer key, so that keys following a pattern are still spread over all the shards
+// (otherwise, with 16 shards, keys 0, 16, 32... would all end up in shard 0).
+func mixBits(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	return x
+}
+
+func (cm *ConcurrentMap[K, V]) shard(key K) *mapShard[K, V] {
+	return cm.shards[cm.hash(key)%uint64(len(cm.shards))]
+}
+
+func (cm *ConcurrentMap[K, V]) Load(key K) (V, bool) {
+	s := cm.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.m[key]
+	return v, ok
+}
+
+func (cm *ConcurrentMap[K, V]) Store(key K, val V) {
+	s := cm.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[key] = val
+}
+
+// Returns the existing value for `key` if present (`loaded` true), otherwise stores & returns `val`
+func (cm *ConcurrentMap[K, V]) LoadOrStore(key K, val V) (actual V, loaded bool) {
+	s := cm.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.m[key]; ok {
+		return v, true
+	}
+	s.m[key] = val
+	return val, false
+}
+
+func (cm *ConcurrentMap[K, V]) Delete(key K) {
+	s := cm.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, key)
+}
+
+// Atomic read-modify-write: stores (and returns) `fn`'s result, `fn` receiving the current value (and whether there is one).
+//
+// The shard stays locked during `fn`, so no other goroutine can update the key in between the read and the write
+// (which a `Load` followed by a `Store` would not guarantee). Therefore, `fn` should be quick and must not use the map.
+func (cm *ConcurrentMap[K, V]) Compute(key K, fn func(old V, loaded bool) V) V {
+	s := cm.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.m[key]
+	val := fn(old, ok)
+	s.m[key] = val
+	return val
+}
+
+func (cm *ConcurrentMap[K, V]) Len() int {
+	n := 0
+	for _, s := range cm.shards {
+		s.mu.RLock()
+		n += len(s.m)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// Iterator over all key/value pairs (see `22-iterators` chapter), usable in a `for k, v := range cm.Range()`.
+//
+// Each shard is copied (under its lock) before its pairs are yielded, so the loop body can itself use the map
+// without deadlocking. The iteration is therefore not a snapshot of the whole map at a single point in time:
+// updates made to shards not yet visited during the loop will be seen.
+func (cm *ConcurrentMap[K, V]) Range() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, s := range cm.shards {
+			s.mu.RLock()
+			pairs := make(map[K]V, len(s.m))
+			for k, v := range s.m {
+				pairs[k] = v
+			}
+			s.mu.RUnlock()
+
+			for k, v := range pairs {
+				if !yield(k, v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func concurrent_map_main() {
+	// Same as `40-mutexes` chapter's main, `inc` becoming a single `Compute` call
+	counters := NewConcurrentMap[string, int](16, nil)
+	var wg sync.WaitGroup
+	doIncrement := func(name string, n int) {
+		for i := 0; i < n; i++ {
+			counters.Compute(name, func(count int, _ bool) int { return count + 1 })
+		}
+		wg.Done()
+	}
+	wg.Add(3)
+	go doIncrement("a", 10000)
+	go doIncrement("a", 10000)
+	go doIncrement("b", 10000)
+	wg.Wait()
+
+	for name, count := range counters.Range() {
+		fmt.Println(name, count)
+	}
+
+	fmt.Println(counters.LoadOrStore("a", 0))
+	fmt.Println(counters.LoadOrStore("c", 5))
+	counters.Delete("c")
+	fmt.Println(counters.Load("c"))
+
+	// Keys of any comparable type: equal keys (`==`) are always in the same shard
+	zeros := NewConcurrentMap[float64, int](16, nil)
+	zeros.Store(0.0, 1)
+	zeros.Store(math.Copysign(0, -1), 2)
+	fmt.Println("0.0 and -0.0:", zeros.Len(), "key")
+}
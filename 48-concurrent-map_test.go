@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"testing"
+)
+
+// Keys equal under `==` must be the same key, whatever their type
+func TestConcurrentMapEqualKeys(t *testing.T) {
+	floats := NewConcurrentMap[float64, int](64, nil)
+	floats.Store(0.0, 1)
+	floats.Store(math.Copysign(0, -1), 2)
+	if n := floats.Len(); n != 1 {
+		t.Errorf("0.0 and -0.0: Len() = %d, want 1", n)
+	}
+
+	type point struct {
+		x, y float64
+		name string
+	}
+	points := NewConcurrentMap[point, int](64, nil)
+	points.Store(point{0, 1, "a"}, 1)
+	points.Store(point{math.Copysign(0, -1), 1, "a"}, 2)
+	if v, _ := points.Load(point{0, 1, "a"}); points.Len() != 1 || v != 2 {
+		t.Errorf("struct keys with 0.0 and -0.0: Len() = %d, value = %d, want 1 key of value 2", points.Len(), v)
+	}
+
+	// Interface keys: same dynamic value --> same key, different dynamic types --> different keys
+	anys := NewConcurrentMap[any, int](64, nil)
+	for _, k := range []any{1, 1, int64(1), "1", [2]int{1, 2}, [2]int{1, 2}, nil, nil} {
+		anys.Compute(k, func(n int, _ bool) int { return n + 1 })
+	}
+	if n := anys.Len(); n != 5 {
+		t.Errorf("interface keys: Len() = %d, want 5", n)
+	}
+}
+
+// Concurrent increments over 64 counters: `Container` vs `sync.Map` vs `ConcurrentMap`.
+//
+// `b.RunParallel` runs the given function in several goroutines (GOMAXPROCS by default),
+// which share the `b.N` iterations through `pb.Next()`.
+var benchmarkKeys = func() []string {
+	keys := make([]string, 64)
+	for i := range keys {
+		keys[i] = fmt.Sprint("counter", i)
+	}
+	return keys
+}()
+
+func BenchmarkCountersContainer(b *testing.B) {
+	c := Container{counters: map[string]int{}}
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.inc(benchmarkKeys[rand.IntN(len(benchmarkKeys))])
+		}
+	})
+}
+
+func BenchmarkCountersSyncMap(b *testing.B) {
+	var m sync.Map
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			key := benchmarkKeys[rand.IntN(len(benchmarkKeys))]
+			// No atomic increment on a `sync.Map`: retry until no one else updated the value in between
+			for {
+				old, loaded := m.LoadOrStore(key, 1)
+				if !loaded || m.CompareAndSwap(key, old, old.(int)+1) {
+					break
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkCountersConcurrentMap(b *testing.B) {
+	for _, shards := range []int{1, 4, 16, 64} {
+		b.Run(fmt.Sprintf("%d-shards", shards), func(b *testing.B) {
+			cm := NewConcurrentMap[string, int](shards, nil)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					cm.Compute(benchmarkKeys[rand.IntN(len(benchmarkKeys))], func(n int, _ bool) int { return n + 1 })
+				}
+			})
+		})
+	}
+}
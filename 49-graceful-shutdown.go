@@ -0,0 +1,283 @@
+// Every concurrent chapter so far ends by sleeping or waiting on a `done` channel. A real program (server, daemon, etc.)
+// instead runs until it's asked to stop, usually by an OS signal:
+// - SIGINT: sent by the terminal on Ctrl+C
+// - SIGTERM: sent by process managers (systemd, Docker, Kubernetes, etc.) before killing the process
+//
+// By default, Go exits right away on those signals, without letting goroutines finish what they're doing (a job
+// half-processed, an HTTP response half-written). `os/signal` lets us receive them on a channel instead,
+// and shut down gracefully:
+// - stop in the reverse order of starting (ex: stop accepting HTTP requests before stopping the workers they feed)
+// - give the whole shutdown a deadline, so a stuck component can't prevent the process from exiting
+// - if a 2nd signal comes during the shutdown (the user hitting Ctrl+C again), exit right away
+//
+// `49-graceful-shutdown_test.go` checks all of that by sending signals to the test process itself (on Linux).
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type Component struct {
+	Name string
+	// Starts the component and returns (long-running work must be started in goroutines).
+	// `ctx` is cancelled when the shutdown begins.
+	Start func(ctx context.Context) error
+	// Stops the component, returning early if `ctx` (carrying the shutdown deadline) is done
+	Stop func(ctx context.Context) error
+}
+
+type Lifecycle struct {
+	components      []Component
+	shutdownTimeout time.Duration
+	signals         []os.Signal
+	// `os.Exit`, replaceable to demonstrate the forced exit without actually exiting
+	exit func(code int)
+}
+
+func NewLifecycle(shutdownTimeout time.Duration) *Lifecycle {
+	return &Lifecycle{
+		shutdownTimeout: shutdownTimeout,
+		signals:         []os.Signal{os.Interrupt, syscall.SIGTERM},
+		exit:            os.Exit,
+	}
+}
+
+// Adds a component, started after (and stopped before) the ones already registered.
+// `start` or `stop` can be nil if there is nothing to do.
+func (l *Lifecycle) Register(name string, start, stop func(ctx context.Context) error) {
+	l.components = append(l.components, Component{Name: name, Start: start, Stop: stop})
+}
+
+// Starts all components, waits for a signal (or `ctx` to be done), then stops all components.
+//
+// If a component fails to start, the ones already started are stopped and the error returned.
+func (l *Lifecycle) Run(ctx context.Context) error {
+	// Buffered: `signal.Notify` does not block when sending, a signal arriving while nobody receives would be lost
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, l.signals...)
+	// Back to the default behaviour (exit) once we return
+	defer signal.Stop(sigs)
+
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+
+	for i, c := range l.components {
+		if c.Start == nil {
+			continue
+		}
+		if err := c.Start(runCtx); err != nil {
+			cancelRun()
+			err = fmt.Errorf("starting %s: %w", c.Name, err)
+			return errors.Join(err, l.shutdown(sigs, l.components[:i]))
+		}
+		fmt.Println("lifecycle: started", c.Name)
+	}
+
+	select {
+	case sig := <-sigs:
+		fmt.Println("lifecycle: received", sig, "- shutting down (send it again to force exit)")
+	case <-ctx.Done():
+		fmt.Println("lifecycle: context done - shutting down")
+	}
+	cancelRun()
+
+	return l.shutdown(sigs, l.components)
+}
+
+// Stops the given components in reverse order, within `shutdownTimeout`
+func (l *Lifecycle) shutdown(sigs <-chan os.Signal, components []Component) error {
+	ctx, cancel := context.WithTimeout(context.Background(), l.shutdownTimeout)
+	defer cancel()
+
+	// Watch for a 2nd signal while stopping
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case sig := <-sigs:
+			fmt.Println("lifecycle: received", sig, "again - forcing exit")
+			cancel()
+			l.exit(1)
+		case <-stopped:
+		}
+	}()
+
+	var errs []error
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+		if c.Stop == nil {
+			continue
+		}
+		// Every component is given a chance to stop, even if a previous one failed or the deadline is exceeded
+		// (they'll see `ctx` done and can at least release what they can without waiting)
+		if err := c.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stopping %s: %w", c.Name, err))
+			continue
+		}
+		fmt.Println("lifecycle: stopped", c.Name)
+	}
+	return errors.Join(errs...)
+}
+
+// Waits for `wg` like `wg.Wait()`, but gives up when `ctx` is done
+func waitContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// A worker pool (see `36-worker-pools` chapter) fed with jobs until the shutdown begins.
+// Stopping waits for the jobs in progress to be finished.
+func workerPoolComponent(workers int, jobDuration time.Duration) (start, stop func(ctx context.Context) error) {
+	var wg sync.WaitGroup
+
+	start = func(ctx context.Context) error {
+		jobs := make(chan int)
+		for w := 1; w <= workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range jobs {
+					time.Sleep(jobDuration)
+					fmt.Println("worker", w, "finished job", j)
+				}
+			}()
+		}
+
+		go func() {
+			// Closing `jobs` lets the workers' `range` loops terminate, once the shutdown began
+			defer close(jobs)
+			for j := 1; ; j++ {
+				select {
+				case jobs <- j:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return nil
+	}
+
+	stop = func(ctx context.Context) error {
+		return waitContext(ctx, &wg)
+	}
+	return start, stop
+}
+
+// A ticker loop (see `35-tickers` chapter) ending with the shutdown
+func tickerComponent(interval time.Duration) (start, stop func(ctx context.Context) error) {
+	var wg sync.WaitGroup
+
+	start = func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case t := <-ticker.C:
+					fmt.Println("tick at", t.Format(time.TimeOnly))
+				}
+			}
+		}()
+		return nil
+	}
+
+	stop = func(ctx context.Context) error {
+		return waitContext(ctx, &wg)
+	}
+	return start, stop
+}
+
+// An HTTP server. `http.Server.Shutdown` stops accepting connections and waits for the requests in progress.
+func httpServerComponent(addr string) (start, stop func(ctx context.Context) error) {
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "hello")
+	})}
+
+	start = func(ctx context.Context) error {
+		// Listening before returning, so a busy port makes `Start` fail (instead of failing later in the goroutine)
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		fmt.Println("http server listening on", ln.Addr())
+		go func() {
+			if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fmt.Println("http server:", err)
+			}
+		}()
+		return nil
+	}
+
+	stop = func(ctx context.Context) error {
+		return srv.Shutdown(ctx)
+	}
+	return start, stop
+}
+
+// Sends `sig` to our own process after `d`, as if it came from the terminal/process manager
+func signalSelfAfter(d time.Duration, sig os.Signal) {
+	time.AfterFunc(d, func() {
+		p, err := os.FindProcess(os.Getpid())
+		if err == nil {
+			err = p.Signal(sig)
+		}
+		if err != nil {
+			fmt.Println("could not signal ourselves:", err)
+		}
+	})
+}
+
+func graceful_shutdown_main() {
+	// 1. Graceful shutdown on SIGINT: the jobs in progress are finished before exiting
+	lc := NewLifecycle(2 * time.Second)
+	start, stop := workerPoolComponent(3, 300*time.Millisecond)
+	lc.Register("workers", start, stop)
+	start, stop = tickerComponent(250 * time.Millisecond)
+	lc.Register("ticker", start, stop)
+	start, stop = httpServerComponent("127.0.0.1:0")
+	lc.Register("http", start, stop)
+
+	signalSelfAfter(time.Second, os.Interrupt)
+	fmt.Println("shutdown error:", lc.Run(context.Background()))
+
+	// 2. A component too slow to stop: the shutdown deadline is exceeded
+	lc = NewLifecycle(500 * time.Millisecond)
+	start, stop = workerPoolComponent(1, 2*time.Second)
+	lc.Register("workers", start, stop)
+
+	signalSelfAfter(100*time.Millisecond, syscall.SIGTERM)
+	fmt.Println("shutdown error:", lc.Run(context.Background()))
+
+	// 3. A 2nd signal during the shutdown forces the exit (here replaced by a print, to not kill the program)
+	lc = NewLifecycle(5 * time.Second)
+	start, stop = workerPoolComponent(1, 2*time.Second)
+	lc.Register("workers", start, stop)
+	lc.exit = func(code int) { fmt.Println("would exit with code", code) }
+
+	signalSelfAfter(100*time.Millisecond, os.Interrupt)
+	signalSelfAfter(300*time.Millisecond, os.Interrupt)
+	fmt.Println("shutdown error:", lc.Run(context.Background()))
+}
@@ -0,0 +1,124 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Those tests send real signals to the test process: `Lifecycle.Run` catches them (`signal.Notify`), so they
+// don't kill it. They must all be received before `Run` returns, as the default behaviour (exit) is then restored.
+
+// Components recording the order in which they are started & stopped
+type recordedLifecycle struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recordedLifecycle) record(event string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.events = append(r.events, event)
+		return nil
+	}
+}
+
+func TestLifecycleStopsInReverseOrder(t *testing.T) {
+	var r recordedLifecycle
+	lc := NewLifecycle(time.Second)
+	for _, name := range []string{"a", "b", "c"} {
+		lc.Register(name, r.record("start "+name), r.record("stop "+name))
+	}
+	// No stop: skipped
+	lc.Register("d", r.record("start d"), nil)
+
+	signalSelfAfter(50*time.Millisecond, syscall.SIGTERM)
+	if err := lc.Run(context.Background()); err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+
+	want := []string{"start a", "start b", "start c", "start d", "stop c", "stop b", "stop a"}
+	if !slices.Equal(r.events, want) {
+		t.Errorf("events = %q, want %q", r.events, want)
+	}
+}
+
+func TestLifecycleStartFailure(t *testing.T) {
+	var r recordedLifecycle
+	lc := NewLifecycle(time.Second)
+	lc.Register("a", r.record("start a"), r.record("stop a"))
+	errBusy := errors.New("port busy")
+	lc.Register("b", func(context.Context) error { return errBusy }, r.record("stop b"))
+
+	// No signal needed: `Run` returns right away
+	err := lc.Run(context.Background())
+	if !errors.Is(err, errBusy) {
+		t.Errorf("Run() = %v, want %v", err, errBusy)
+	}
+	// Only the components already started are stopped
+	if want := []string{"start a", "stop a"}; !slices.Equal(r.events, want) {
+		t.Errorf("events = %q, want %q", r.events, want)
+	}
+}
+
+func TestLifecycleShutdownDeadline(t *testing.T) {
+	var r recordedLifecycle
+	lc := NewLifecycle(100 * time.Millisecond)
+	lc.Register("fast", nil, r.record("stop fast"))
+	// Never stops by itself: gives up when the deadline is exceeded
+	lc.Register("stuck", nil, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	signalSelfAfter(50*time.Millisecond, os.Interrupt)
+	start := time.Now()
+	err := lc.Run(context.Background())
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed > time.Second {
+		t.Errorf("Run() took %v, want about 150ms", elapsed)
+	}
+	// The components after the stuck one (in stop order) still get stopped
+	if want := []string{"stop fast"}; !slices.Equal(r.events, want) {
+		t.Errorf("events = %q, want %q", r.events, want)
+	}
+}
+
+func TestLifecycleSecondSignalForcesExit(t *testing.T) {
+	lc := NewLifecycle(10 * time.Second)
+	exitCodes := make(chan int, 1)
+	lc.exit = func(code int) { exitCodes <- code }
+	// Stops only when the shutdown is cancelled by the 2nd signal (well before the 10s deadline)
+	lc.Register("slow", nil, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	signalSelfAfter(50*time.Millisecond, os.Interrupt)
+	signalSelfAfter(150*time.Millisecond, os.Interrupt)
+	err := lc.Run(context.Background())
+
+	select {
+	case code := <-exitCodes:
+		if code != 1 {
+			t.Errorf("exit code = %d, want 1", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("exit not called after the 2nd signal")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() = %v, want %v", err, context.Canceled)
+	}
+}
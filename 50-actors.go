@@ -0,0 +1,384 @@
+// The channel chapters pass messages between goroutines, but there is no structure for long-lived concurrent
+// entities owning some state (like the `Container` counters of `40-mutexes` chapter).
+//
+// The actor model gives them one: an actor is a goroutine owning private state, and the only way to interact
+// with it is to send it messages, which it processes one at a time from its mailbox (a channel).
+// Since only the actor's goroutine ever touches its state, no mutex is needed ("share memory by communicating").
+//
+// - `Tell`: fire-and-forget, the message is queued and the sender moves on
+// - `Ask`: the sender waits for the actor's reply (with a context for timeouts/cancellation)
+//
+// When an actor panics while processing a message, its supervisor strategy decides what happens next:
+// restart it with a fresh state (after a backoff delay), stop it, or escalate the failure to its parent.
+// See `50-actors_test.go` for tests of each strategy.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// What an actor does with its messages, of type `M`, replying with values of type `R`.
+//
+// A new `Behavior` is created (by the factory given to `Spawn`) when the actor starts and on each restart,
+// so a restart resets the actor's state.
+type Behavior[M, R any] interface {
+	Receive(msg M) (R, error)
+}
+
+// Optional lifecycle hooks, that a `Behavior` can implement.
+// Discovered with a type assertion at runtime (like `detectCircle` does for `circle` in `18-interfaces` chapter).
+type (
+	// Called before the 1st message (at start and after each restart)
+	actorPreStarter interface{ PreStart() }
+	// Called on the failed behavior, before it is replaced by a new one
+	actorPreRestarter interface{ PreRestart(reason error) }
+	// Called once the actor stopped processing messages
+	actorPostStopper interface{ PostStop() }
+)
+
+type SupervisorStrategy int
+
+const (
+	// Replaces the failed behavior with a new one and goes on with the next messages
+	SupervisorRestart SupervisorStrategy = iota
+	// Stops the actor: messages left in the mailbox are dropped
+	SupervisorStop
+	// Stops the actor and reports the failure to `ActorOptions.OnEscalate`, which decides for the actor
+	SupervisorEscalate
+)
+
+type ActorOptions struct {
+	Name        string
+	MailboxSize int
+	Strategy    SupervisorStrategy
+	// Backoff between restarts (see `43-context-timeouts` chapter). `MaxAttempts` is the max number of restarts,
+	// after which the actor stops (0 for no limit).
+	RestartPolicy RetryPolicy
+	// Called with the failure when the strategy is `SupervisorEscalate`.
+	// If nil, the panic is re-raised (which crashes the program, like any unrecovered panic in a goroutine).
+	OnEscalate func(name string, err error)
+}
+
+var ErrActorStopped = errors.New("actor stopped")
+
+// Error replied to the `Ask` whose message made the actor panic
+type ActorPanicError struct {
+	Actor string
+	Value any
+}
+
+func (e *ActorPanicError) Error() string {
+	return fmt.Sprintf("actor %s panicked: %v", e.Actor, e.Value)
+}
+
+type actorReply[R any] struct {
+	val R
+	err error
+}
+
+type envelope[M, R any] struct {
+	msg M
+	// nil for `Tell`. Buffered (1), so the actor never blocks replying to an `Ask` which gave up.
+	reply chan actorReply[R]
+}
+
+type Actor[M, R any] struct {
+	opts    ActorOptions
+	factory func() Behavior[M, R]
+	mailbox chan envelope[M, R]
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	// Closed once the actor's goroutine has exited
+	done chan struct{}
+	// Why the actor stopped (nil if by `Stop`), set before `done` is closed
+	err error
+}
+
+// Creates an actor and starts its goroutine
+func Spawn[M, R any](opts ActorOptions, factory func() Behavior[M, R]) *Actor[M, R] {
+	a := &Actor[M, R]{
+		opts:    opts,
+		factory: factory,
+		mailbox: make(chan envelope[M, R], opts.MailboxSize),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Queues `msg` without waiting for it to be processed (only waits for room in the mailbox)
+func (a *Actor[M, R]) Tell(msg M) error {
+	// Checked first: when both cases are ready (a stopped actor with room left in its mailbox), `select` picks one
+	// at random, and the message would be queued in a mailbox nobody reads anymore
+	select {
+	case <-a.done:
+		return ErrActorStopped
+	default:
+	}
+
+	select {
+	case a.mailbox <- envelope[M, R]{msg: msg}:
+		return nil
+	case <-a.done:
+		return ErrActorStopped
+	}
+}
+
+// Queues `msg` and waits for the actor's reply
+func (a *Actor[M, R]) Ask(ctx context.Context, msg M) (R, error) {
+	var zero R
+	env := envelope[M, R]{msg: msg, reply: make(chan actorReply[R], 1)}
+
+	// Same as in `Tell`
+	select {
+	case <-a.done:
+		return zero, ErrActorStopped
+	default:
+	}
+
+	select {
+	case a.mailbox <- env:
+	case <-a.done:
+		return zero, ErrActorStopped
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+
+	select {
+	case r := <-env.reply:
+		return r.val, r.err
+	case <-a.done:
+		// The actor may have replied before stopping (ex: the message it crashed on): `select` picked `done` at random
+		select {
+		case r := <-env.reply:
+			return r.val, r.err
+		default:
+			return zero, ErrActorStopped
+		}
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// Asks the actor to stop after the message in progress (if any), and waits for it
+func (a *Actor[M, R]) Stop() {
+	a.stopOnce.Do(func() { close(a.stop) })
+	<-a.done
+}
+
+// Closed once the actor is stopped
+func (a *Actor[M, R]) Done() <-chan struct{} {
+	return a.done
+}
+
+// Why the actor stopped: nil if stopped by `Stop`, the failure otherwise. Only valid once `Done` is closed.
+func (a *Actor[M, R]) Err() error {
+	return a.err
+}
+
+func (a *Actor[M, R]) run() {
+	defer close(a.done)
+
+	behavior := a.start()
+	restarts := 0
+	for {
+		select {
+		case <-a.stop:
+			a.postStop(behavior)
+			return
+
+		case env := <-a.mailbox:
+			err := a.receive(behavior, env)
+			if err == nil {
+				continue
+			}
+
+			switch a.opts.Strategy {
+			case SupervisorRestart:
+				policy := a.opts.RestartPolicy
+				if policy.MaxAttempts > 0 && restarts >= policy.MaxAttempts {
+					a.err = fmt.Errorf("too many restarts: %w", err)
+					a.postStop(behavior)
+					return
+				}
+				if h, ok := behavior.(actorPreRestarter); ok {
+					h.PreRestart(err)
+				}
+				// Waiting, instead of restarting right away, avoids a tight loop of crashes
+				// if the failure comes from something external (ex: a database down)
+				t := time.NewTimer(policy.backoff(restarts))
+				select {
+				case <-t.C:
+				case <-a.stop:
+					t.Stop()
+					a.postStop(behavior)
+					return
+				}
+				restarts++
+				behavior = a.start()
+
+			case SupervisorStop:
+				a.err = err
+				a.postStop(behavior)
+				return
+
+			case SupervisorEscalate:
+				a.err = err
+				a.postStop(behavior)
+				if a.opts.OnEscalate == nil {
+					panic(err)
+				}
+				a.opts.OnEscalate(a.opts.Name, err)
+				return
+			}
+		}
+	}
+}
+
+func (a *Actor[M, R]) start() Behavior[M, R] {
+	behavior := a.factory()
+	if h, ok := behavior.(actorPreStarter); ok {
+		h.PreStart()
+	}
+	return behavior
+}
+
+func (a *Actor[M, R]) postStop(behavior Behavior[M, R]) {
+	if h, ok := behavior.(actorPostStopper); ok {
+		h.PostStop()
+	}
+}
+
+// Processes 1 message, replying to it if it's an `Ask`.
+// Returns a non-nil error only if the behavior panicked (an error returned by `Receive` is just a reply).
+func (a *Actor[M, R]) receive(behavior Behavior[M, R], env envelope[M, R]) (failure error) {
+	defer func() {
+		if r := recover(); r != nil {
+			failure = &ActorPanicError{Actor: a.opts.Name, Value: r}
+			if env.reply != nil {
+				env.reply <- actorReply[R]{err: failure}
+			}
+		}
+	}()
+
+	val, err := behavior.Receive(env.msg)
+	if env.reply != nil {
+		env.reply <- actorReply[R]{val: val, err: err}
+	}
+	return nil
+}
+
+// 1. `Container` counters (see `40-mutexes` chapter) as an actor: no mutex, the map is only used by the actor's goroutine
+
+type counterMsg struct {
+	name string
+	// Only reads the counter if true, increments it otherwise
+	get bool
+}
+
+type countersActor struct {
+	counters map[string]int
+}
+
+func (c *countersActor) Receive(msg counterMsg) (int, error) {
+	if !msg.get {
+		c.counters[msg.name]++
+	}
+	return c.counters[msg.name], nil
+}
+
+// 2. The `ServerState` server simulation (see `19-enums` chapter) as an actor
+
+type serverMsg struct {
+	// Forces the state if set, otherwise moves to the next state (`transition`)
+	force *ServerState
+}
+
+type serverActor struct {
+	state ServerState
+}
+
+func (s *serverActor) Receive(msg serverMsg) (ServerState, error) {
+	if msg.force != nil {
+		s.state = *msg.force
+	} else {
+		// Panics on an unknown state: the supervisor will restart the server in `StateIdle`
+		s.state = transition(s.state)
+	}
+	return s.state, nil
+}
+
+func (s *serverActor) PreStart() {
+	fmt.Println("server: starting in state", s.state)
+}
+
+func (s *serverActor) PreRestart(reason error) {
+	fmt.Println("server: restarting after:", reason)
+}
+
+func (s *serverActor) PostStop() {
+	fmt.Println("server: stopped in state", s.state)
+}
+
+func actors_main() {
+	ctx := context.Background()
+
+	counters := Spawn(ActorOptions{Name: "counters", MailboxSize: 100}, func() Behavior[counterMsg, int] {
+		return &countersActor{counters: map[string]int{}}
+	})
+
+	var wg sync.WaitGroup
+	doIncrement := func(name string, n int) {
+		for i := 0; i < n; i++ {
+			counters.Tell(counterMsg{name: name})
+		}
+		wg.Done()
+	}
+	wg.Add(3)
+	go doIncrement("a", 10000)
+	go doIncrement("a", 10000)
+	go doIncrement("b", 10000)
+	wg.Wait()
+
+	// Messages are processed in order: the `Ask`s come after all the increments
+	a, _ := counters.Ask(ctx, counterMsg{name: "a", get: true})
+	b, _ := counters.Ask(ctx, counterMsg{name: "b", get: true})
+	fmt.Println("a:", a, "b:", b)
+	counters.Stop()
+
+	restartPolicy := RetryPolicy{MaxAttempts: 3, InitialDelay: 50 * time.Millisecond, Multiplier: 2}
+	server := Spawn(ActorOptions{Name: "server", Strategy: SupervisorRestart, RestartPolicy: restartPolicy}, func() Behavior[serverMsg, ServerState] {
+		return &serverActor{state: StateIdle}
+	})
+
+	fmt.Println(server.Ask(ctx, serverMsg{}))
+	fmt.Println(server.Ask(ctx, serverMsg{}))
+	invalid := ServerState(42)
+	fmt.Println(server.Ask(ctx, serverMsg{force: &invalid}))
+	// `transition` panics on the invalid state --> the server is restarted (back to `StateIdle`)
+	fmt.Println(server.Ask(ctx, serverMsg{}))
+	fmt.Println(server.Ask(ctx, serverMsg{}))
+	server.Stop()
+
+	// Same crash, but escalated to the "parent" (here, just a print)
+	server = Spawn(ActorOptions{
+		Name:     "server",
+		Strategy: SupervisorEscalate,
+		OnEscalate: func(name string, err error) {
+			fmt.Println("parent: child", name, "failed:", err)
+		},
+	}, func() Behavior[serverMsg, ServerState] {
+		return &serverActor{state: StateIdle}
+	})
+	server.Tell(serverMsg{force: &invalid})
+	server.Tell(serverMsg{})
+	<-server.Done()
+	_, err := server.Ask(ctx, serverMsg{})
+	fmt.Println("after escalation:", err, "- stopped because:", server.Err())
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type echoActor struct{}
+
+func (echoActor) Receive(msg int) (int, error) {
+	return msg, nil
+}
+
+// Even with room left in the mailbox, a stopped actor refuses every message
+func TestActorStoppedRefusesMessages(t *testing.T) {
+	a := Spawn(ActorOptions{Name: "echo", MailboxSize: 10}, func() Behavior[int, int] { return echoActor{} })
+	if v, err := a.Ask(context.Background(), 1); v != 1 || err != nil {
+		t.Fatalf("Ask(1) = %d, %v, want 1, nil", v, err)
+	}
+	a.Stop()
+
+	for i := range 100 {
+		if err := a.Tell(i); !errors.Is(err, ErrActorStopped) {
+			t.Fatalf("Tell after Stop = %v, want %v", err, ErrActorStopped)
+		}
+		if _, err := a.Ask(context.Background(), i); !errors.Is(err, ErrActorStopped) {
+			t.Fatalf("Ask after Stop = %v, want %v", err, ErrActorStopped)
+		}
+	}
+}
+
+// Records the lifecycle hooks of the `crashingActor`s created by the same factory
+type actorHooks struct {
+	mu     sync.Mutex
+	events []string
+	// Number of behaviors created so far
+	generation int
+}
+
+func (h *actorHooks) record(format string, args ...any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, fmt.Sprintf(format, args...))
+}
+
+func (h *actorHooks) Events() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.events)
+}
+
+func (h *actorHooks) factory() Behavior[string, string] {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.generation++
+	return &crashingActor{hooks: h, generation: h.generation}
+}
+
+// Panics on the "crash" message, replies to the others with the number of the behavior receiving them
+type crashingActor struct {
+	hooks      *actorHooks
+	generation int
+}
+
+func (c *crashingActor) Receive(msg string) (string, error) {
+	if msg == "crash" {
+		panic("crash")
+	}
+	return fmt.Sprintf("%s from #%d", msg, c.generation), nil
+}
+
+func (c *crashingActor) PreStart() {
+	c.hooks.record("PreStart #%d", c.generation)
+}
+
+func (c *crashingActor) PreRestart(reason error) {
+	c.hooks.record("PreRestart #%d: %v", c.generation, reason)
+}
+
+func (c *crashingActor) PostStop() {
+	c.hooks.record("PostStop #%d", c.generation)
+}
+
+func askCrash(t *testing.T, a *Actor[string, string]) {
+	t.Helper()
+	var pe *ActorPanicError
+	if _, err := a.Ask(context.Background(), "crash"); !errors.As(err, &pe) || pe.Actor != "crasher" || pe.Value != "crash" {
+		t.Fatalf("Ask(crash) = %v, want an *ActorPanicError", err)
+	}
+}
+
+func awaitStopped(t *testing.T, a *Actor[string, string]) {
+	t.Helper()
+	select {
+	case <-a.Done():
+	case <-time.After(time.Second):
+		t.Fatal("actor still running")
+	}
+}
+
+func TestActorRestartWithBackoff(t *testing.T) {
+	VerifyNoLeaks(t)
+	var hooks actorHooks
+	policy := RetryPolicy{InitialDelay: 50 * time.Millisecond, Multiplier: 2}
+	a := Spawn(ActorOptions{Name: "crasher", Strategy: SupervisorRestart, RestartPolicy: policy}, hooks.factory)
+
+	if v, err := a.Ask(context.Background(), "hello"); v != "hello from #1" || err != nil {
+		t.Fatalf("Ask = %q, %v, want %q", v, err, "hello from #1")
+	}
+	// Each restart waits longer: 50ms, then 100ms
+	for i, wantDelay := range []time.Duration{50 * time.Millisecond, 100 * time.Millisecond} {
+		askCrash(t, a)
+		start := time.Now()
+		v, err := a.Ask(context.Background(), "hello")
+		if elapsed := time.Since(start); elapsed < wantDelay {
+			t.Errorf("restart %d after %v, want at least %v", i+1, elapsed, wantDelay)
+		}
+		// The message is processed by the new behavior
+		if want := fmt.Sprintf("hello from #%d", i+2); v != want || err != nil {
+			t.Errorf("Ask after restart %d = %q, %v, want %q", i+1, v, err, want)
+		}
+	}
+	a.Stop()
+	if a.Err() != nil {
+		t.Errorf("Err() = %v, want nil after Stop", a.Err())
+	}
+
+	want := []string{
+		"PreStart #1",
+		"PreRestart #1: actor crasher panicked: crash",
+		"PreStart #2",
+		"PreRestart #2: actor crasher panicked: crash",
+		"PreStart #3",
+		"PostStop #3",
+	}
+	if got := hooks.Events(); !slices.Equal(got, want) {
+		t.Errorf("hooks = %q, want %q", got, want)
+	}
+}
+
+func TestActorRestartMaxAttempts(t *testing.T) {
+	VerifyNoLeaks(t)
+	var hooks actorHooks
+	policy := RetryPolicy{MaxAttempts: 1, InitialDelay: time.Millisecond}
+	a := Spawn(ActorOptions{Name: "crasher", MailboxSize: 10, Strategy: SupervisorRestart, RestartPolicy: policy}, hooks.factory)
+
+	askCrash(t, a)
+	askCrash(t, a)
+	// The 2nd crash exceeds the only restart allowed
+	awaitStopped(t, a)
+	var pe *ActorPanicError
+	if err := a.Err(); !errors.As(err, &pe) || !strings.Contains(err.Error(), "too many restarts") {
+		t.Errorf("Err() = %v, want too many restarts of an *ActorPanicError", err)
+	}
+	if err := a.Tell("hello"); !errors.Is(err, ErrActorStopped) {
+		t.Errorf("Tell after the stop = %v, want %v", err, ErrActorStopped)
+	}
+
+	want := []string{"PreStart #1", "PreRestart #1: actor crasher panicked: crash", "PreStart #2", "PostStop #2"}
+	if got := hooks.Events(); !slices.Equal(got, want) {
+		t.Errorf("hooks = %q, want %q", got, want)
+	}
+}
+
+func TestActorStopStrategy(t *testing.T) {
+	VerifyNoLeaks(t)
+	var hooks actorHooks
+	a := Spawn(ActorOptions{Name: "crasher", MailboxSize: 10, Strategy: SupervisorStop}, hooks.factory)
+
+	// The messages queued after the crash are dropped
+	for _, msg := range []string{"crash", "hello", "hello"} {
+		a.Tell(msg)
+	}
+	awaitStopped(t, a)
+	var pe *ActorPanicError
+	if err := a.Err(); !errors.As(err, &pe) || pe.Value != "crash" {
+		t.Errorf("Err() = %v, want an *ActorPanicError", err)
+	}
+	if _, err := a.Ask(context.Background(), "hello"); !errors.Is(err, ErrActorStopped) {
+		t.Errorf("Ask after the stop = %v, want %v", err, ErrActorStopped)
+	}
+
+	// Never restarted
+	want := []string{"PreStart #1", "PostStop #1"}
+	if got := hooks.Events(); !slices.Equal(got, want) {
+		t.Errorf("hooks = %q, want %q", got, want)
+	}
+}
+
+func TestActorEscalate(t *testing.T) {
+	VerifyNoLeaks(t)
+	var hooks actorHooks
+	type failure struct {
+		name string
+		err  error
+	}
+	escalated := make(chan failure, 1)
+	a := Spawn(ActorOptions{
+		Name:       "crasher",
+		Strategy:   SupervisorEscalate,
+		OnEscalate: func(name string, err error) { escalated <- failure{name, err} },
+	}, hooks.factory)
+
+	askCrash(t, a)
+	select {
+	case f := <-escalated:
+		var pe *ActorPanicError
+		if f.name != "crasher" || !errors.As(f.err, &pe) {
+			t.Errorf("escalated %q, %v, want crasher, an *ActorPanicError", f.name, f.err)
+		}
+		if f.err != a.Err() {
+			t.Errorf("escalated %v, but Err() = %v", f.err, a.Err())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("failure not escalated to the parent")
+	}
+	awaitStopped(t, a)
+
+	// The child is stopped (PostStop) before the parent hears about it
+	want := []string{"PreStart #1", "PostStop #1"}
+	if got := hooks.Events(); !slices.Equal(got, want) {
+		t.Errorf("hooks = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,408 @@
+// In `36-worker-pools` chapter, the jobs only live in a buffered channel: if the program crashes (or is killed)
+// before the workers are done, the jobs left are lost, and nobody knows which ones were actually processed.
+//
+// Here we make the queue durable with a write-ahead log (WAL): before a job is handed to a worker, it is appended
+// to a file (and flushed to disk). Once a worker is done with it, it acknowledges (acks) the job, which appends
+// an "ack" record. On restart, replaying the log gives back the jobs never acked, which are processed again.
+// |--> a job might be processed twice (crash after processing, before the ack), but never lost ("at-least-once").
+//
+// As the log only grows, it is compacted from time to time: rewritten with only the jobs not acked yet.
+//
+// Consumers still use a channel (`for job := range q.Jobs()`), like the workers of `36-worker-pools` chapter.
+//
+// Everything is tested on a temporary directory in `51-durable-queue_test.go`, crashes included (a crash being
+// simulated by closing the queue without acking, or by cutting the log off in the middle of a record).
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+type DurableJob[T any] struct {
+	ID      uint64
+	Payload T
+}
+
+// A line of the log, JSON-encoded
+type walRecord[T any] struct {
+	// "job", "ack", or "seq" (written by the compaction, to not reuse IDs of acked jobs)
+	Op      string `json:"op"`
+	ID      uint64 `json:"id"`
+	Payload T      `json:"payload,omitempty"`
+}
+
+type DurableQueue[T any] struct {
+	path string
+	// Compacts automatically after that many acks (0 to disable)
+	compactEvery int
+
+	mu      sync.Mutex
+	file    *os.File
+	nextID  uint64
+	pending map[uint64]T
+	// IDs not handed to a consumer yet, in order
+	ready []uint64
+	acks  int
+	// Wakes up the dispatcher when `ready` gets a new ID (buffered 1: 1 pending wake-up is enough)
+	notify chan struct{}
+
+	jobs      chan DurableJob[T]
+	closed    chan struct{}
+	closeOnce sync.Once
+	// Closed when the dispatcher goroutine has exited
+	dispatched chan struct{}
+}
+
+var ErrUnknownJob = errors.New("unknown or already acked job")
+
+// Opens (or creates) the queue's log at `path`, replaying it: jobs not acked will be delivered again on `Jobs()`
+func OpenDurableQueue[T any](path string, compactEvery int) (*DurableQueue[T], error) {
+	q := &DurableQueue[T]{
+		path:         path,
+		compactEvery: compactEvery,
+		nextID:       1,
+		pending:      map[uint64]T{},
+		notify:       make(chan struct{}, 1),
+		jobs:         make(chan DurableJob[T]),
+		closed:       make(chan struct{}),
+		dispatched:   make(chan struct{}),
+	}
+
+	if err := q.replay(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	q.file = f
+
+	for id := range q.pending {
+		q.ready = append(q.ready, id)
+	}
+	slices.Sort(q.ready)
+	if len(q.ready) > 0 {
+		q.notify <- struct{}{}
+	}
+
+	go q.dispatch()
+	return q, nil
+}
+
+func (q *DurableQueue[T]) replay() error {
+	f, err := os.Open(q.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Not a `bufio.Scanner`: its lines are limited to 64KiB, so 1 big payload would make the log impossible to reopen
+	r := bufio.NewReader(f)
+	// Size of the log up to the end of the last valid record
+	var validSize int64
+	var badLine string
+	for lineNum := 1; ; lineNum++ {
+		line, err := r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if len(line) == 0 {
+			break
+		}
+		if badLine != "" {
+			// Only the very last line may be broken (see below), not one in the middle of the log
+			return fmt.Errorf("corrupted log %s: %s", q.path, badLine)
+		}
+
+		if errors.Is(err, io.EOF) {
+			// Every record ends with '\n': without it, the write was cut off by a crash (even if what's there is
+			// valid JSON), and the next record would be appended on the same line
+			badLine = fmt.Sprintf("line %d: missing end of line", lineNum)
+			break
+		}
+
+		var rec walRecord[T]
+		if err := json.Unmarshal(line, &rec); err != nil {
+			// The last line might have been partially written when the program crashed: that record was
+			// never confirmed to its writer (`Enqueue`/`Ack` did not return), so it can safely be ignored
+			badLine = fmt.Sprintf("line %d: %v", lineNum, err)
+			continue
+		}
+		validSize += int64(len(line))
+
+		switch rec.Op {
+		case "job":
+			q.pending[rec.ID] = rec.Payload
+		case "ack":
+			delete(q.pending, rec.ID)
+		}
+		q.nextID = max(q.nextID, rec.ID+1)
+	}
+
+	if badLine != "" {
+		// Cut the broken record off, otherwise the next record would be appended right after it (on the same line)
+		return os.Truncate(q.path, validSize)
+	}
+	return nil
+}
+
+// Appends a record to the log, and waits for it to be on disk.
+// Must be called with `q.mu` locked.
+func (q *DurableQueue[T]) appendLocked(rec walRecord[T]) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := q.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	// Without `Sync`, the write might only be in the OS's memory, and be lost on a power failure
+	return q.file.Sync()
+}
+
+// Durably adds a job, and returns its ID. The job is delivered on `Jobs()` in order.
+func (q *DurableQueue[T]) Enqueue(payload T) (uint64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.isClosed() {
+		return 0, ErrQueueClosed
+	}
+
+	id := q.nextID
+	if err := q.appendLocked(walRecord[T]{Op: "job", ID: id, Payload: payload}); err != nil {
+		return 0, err
+	}
+	q.nextID++
+	q.pending[id] = payload
+	q.ready = append(q.ready, id)
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+		// A wake-up is already pending
+	}
+	return id, nil
+}
+
+// Marks a job as done: it won't be delivered again, even after a restart
+func (q *DurableQueue[T]) Ack(id uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.pending[id]; !ok {
+		return ErrUnknownJob
+	}
+
+	if err := q.appendLocked(walRecord[T]{Op: "ack", ID: id}); err != nil {
+		return err
+	}
+	delete(q.pending, id)
+
+	q.acks++
+	if q.compactEvery > 0 && q.acks >= q.compactEvery {
+		return q.compactLocked()
+	}
+	return nil
+}
+
+// Channel delivering the jobs, closed by `Close`
+func (q *DurableQueue[T]) Jobs() <-chan DurableJob[T] {
+	return q.jobs
+}
+
+// Number of jobs not acked yet
+func (q *DurableQueue[T]) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// Rewrites the log with only the jobs not acked yet
+func (q *DurableQueue[T]) Compact() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.compactLocked()
+}
+
+func (q *DurableQueue[T]) compactLocked() error {
+	// Written to a temporary file first, then renamed over the log: a rename is atomic, so if we crash
+	// in the middle of the compaction, we still have either the old log or the new one (never half of it)
+	tmpPath := q.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	// `Encode` adds the '\n' itself
+	err = enc.Encode(walRecord[T]{Op: "seq", ID: q.nextID - 1})
+	ids := make([]uint64, 0, len(q.pending))
+	for id := range q.pending {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		if err == nil {
+			err = enc.Encode(walRecord[T]{Op: "job", ID: id, Payload: q.pending[id]})
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, q.path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("compacting %s: %w", q.path, err)
+	}
+
+	// Our file handle still points to the old (now deleted) log: reopen the new one
+	q.file.Close()
+	q.file, err = os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	q.acks = 0
+	return err
+}
+
+func (q *DurableQueue[T]) isClosed() bool {
+	select {
+	case <-q.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Hands the ready jobs, one at a time, to the consumers
+func (q *DurableQueue[T]) dispatch() {
+	defer close(q.dispatched)
+	defer close(q.jobs)
+
+	for {
+		q.mu.Lock()
+		var job DurableJob[T]
+		found := false
+		for len(q.ready) > 0 && !found {
+			job.ID, q.ready = q.ready[0], q.ready[1:]
+			// Might have been acked in the meantime (ex: by a consumer acking an ID it knew from a previous run)
+			job.Payload, found = q.pending[job.ID]
+		}
+		q.mu.Unlock()
+
+		if !found {
+			select {
+			case <-q.notify:
+				continue
+			case <-q.closed:
+				return
+			}
+		}
+
+		select {
+		case q.jobs <- job:
+		case <-q.closed:
+			return
+		}
+	}
+}
+
+// Stops delivering jobs (closing `Jobs()`) and closes the log.
+// Jobs delivered but not acked yet will be delivered again when the log is reopened.
+func (q *DurableQueue[T]) Close() error {
+	q.closeOnce.Do(func() { close(q.closed) })
+	<-q.dispatched
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.file.Close()
+}
+
+// Like `workerCh36`, but acks each job once done
+func durableWorker(id int, q *DurableQueue[int], wg *sync.WaitGroup) {
+	defer wg.Done()
+	for job := range q.Jobs() {
+		fmt.Println("worker", id, "started  job", job.ID)
+		time.Sleep(100 * time.Millisecond)
+		fmt.Println("worker", id, "finished job", job.ID, "result", job.Payload*2)
+		if err := q.Ack(job.ID); err != nil {
+			fmt.Println("ack failed:", err)
+		}
+	}
+}
+
+func printLog(path string) {
+	content, _ := os.ReadFile(path)
+	fmt.Printf("--- %s (%d bytes)\n%s", filepath.Base(path), len(content), content)
+}
+
+func durable_queue_main() {
+	dir, err := os.MkdirTemp("", "durable-queue")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jobs.wal")
+
+	// 1st run: 5 jobs enqueued, only 2 processed before a "crash"
+	q, err := OpenDurableQueue[int](path, 0)
+	if err != nil {
+		panic(err)
+	}
+	for j := 1; j <= 5; j++ {
+		q.Enqueue(j * 10)
+	}
+	for range 2 {
+		job := <-q.Jobs()
+		fmt.Println("processed job", job.ID, "before the crash")
+		q.Ack(job.ID)
+	}
+	// Received, but the crash happens before the ack
+	fmt.Println("job", (<-q.Jobs()).ID, "in progress when crashing")
+	q.Close()
+	printLog(path)
+
+	// 2nd run: the 3 jobs not acked are replayed, and processed by a pool of 3 workers
+	q, err = OpenDurableQueue[int](path, 0)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("pending after restart:", q.Pending())
+
+	var wg sync.WaitGroup
+	for w := 1; w <= 3; w++ {
+		wg.Add(1)
+		go durableWorker(w, q, &wg)
+	}
+	q.Enqueue(60)
+	for q.Pending() > 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	q.Close()
+	wg.Wait()
+	printLog(path)
+
+	// Compaction: all jobs acked, only the ID sequence is left
+	q, _ = OpenDurableQueue[int](path, 0)
+	q.Compact()
+	q.Close()
+	printLog(path)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestQueue(t *testing.T, path string, compactEvery int) *DurableQueue[string] {
+	t.Helper()
+	q, err := OpenDurableQueue[string](path, compactEvery)
+	if err != nil {
+		t.Fatalf("OpenDurableQueue(%s) = %v", path, err)
+	}
+	return q
+}
+
+func enqueueAll(t *testing.T, q *DurableQueue[string], payloads ...string) []uint64 {
+	t.Helper()
+	var ids []uint64
+	for _, p := range payloads {
+		id, err := q.Enqueue(p)
+		if err != nil {
+			t.Fatalf("Enqueue(%q) = %v", p, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Receives `n` jobs from the queue, failing the test if they take too long to come
+func receiveJobs(t *testing.T, q *DurableQueue[string], n int) []DurableJob[string] {
+	t.Helper()
+	var jobs []DurableJob[string]
+	for range n {
+		select {
+		case job := <-q.Jobs():
+			jobs = append(jobs, job)
+		case <-time.After(time.Second):
+			t.Fatalf("received %d jobs, want %d", len(jobs), n)
+		}
+	}
+	return jobs
+}
+
+func jobPayloads(jobs []DurableJob[string]) []string {
+	var payloads []string
+	for _, job := range jobs {
+		payloads = append(payloads, job.Payload)
+	}
+	return payloads
+}
+
+func TestDurableQueueReplaysUnackedJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+	q := openTestQueue(t, path, 0)
+	enqueueAll(t, q, "a", "b", "c", "d")
+	jobs := receiveJobs(t, q, 3)
+	// "c" was received but not acked ("crash" while processing it)
+	for _, job := range jobs[:2] {
+		if err := q.Ack(job.ID); err != nil {
+			t.Fatalf("Ack(%d) = %v", job.ID, err)
+		}
+	}
+	if err := q.Ack(jobs[0].ID); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("2nd Ack(%d) = %v, want %v", jobs[0].ID, err, ErrUnknownJob)
+	}
+	q.Close()
+
+	q = openTestQueue(t, path, 0)
+	defer q.Close()
+	if n := q.Pending(); n != 2 {
+		t.Errorf("Pending() after reopen = %d, want 2", n)
+	}
+	if got := jobPayloads(receiveJobs(t, q, 2)); !slices.Equal(got, []string{"c", "d"}) {
+		t.Errorf("replayed jobs = %q, want [c d]", got)
+	}
+	// IDs keep increasing: an ID is never reused
+	if ids := enqueueAll(t, q, "e"); ids[0] != 5 {
+		t.Errorf("ID after reopen = %d, want 5", ids[0])
+	}
+}
+
+func TestDurableQueueCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+	// Compacts on every 2nd ack
+	q := openTestQueue(t, path, 2)
+	enqueueAll(t, q, "a", "b", "c")
+	for _, job := range receiveJobs(t, q, 2) {
+		if err := q.Ack(job.ID); err != nil {
+			t.Fatalf("Ack(%d) = %v", job.ID, err)
+		}
+	}
+	q.Close()
+
+	// Only the sequence & the job not acked are left
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(content), "\n"); lines != 2 {
+		t.Errorf("compacted log has %d lines, want 2:\n%s", lines, content)
+	}
+
+	q = openTestQueue(t, path, 2)
+	defer q.Close()
+	if got := jobPayloads(receiveJobs(t, q, 1)); !slices.Equal(got, []string{"c"}) {
+		t.Errorf("jobs after compaction = %q, want [c]", got)
+	}
+	if ids := enqueueAll(t, q, "d"); ids[0] != 4 {
+		t.Errorf("ID after compaction = %d, want 4", ids[0])
+	}
+}
+
+// Simulates a crash in the middle of writing the last record
+func TestDurableQueueTornLastRecord(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		tail string
+	}{
+		{"broken json", `{"op":"job","id":3,"pay`},
+		{"broken json with newline", "{\"op\":\"job\",\"id\":3,\"pay\n"},
+		// Valid JSON, but the '\n' wasn't written: the next record must not end up on the same line
+		{"missing newline", `{"op":"job","id":3,"payload":"c"}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "jobs.wal")
+			q := openTestQueue(t, path, 0)
+			enqueueAll(t, q, "a", "b")
+			q.Close()
+
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.WriteString(tc.tail)
+			f.Close()
+
+			// The torn record was never confirmed: it's dropped, and the queue keeps working
+			q = openTestQueue(t, path, 0)
+			if n := q.Pending(); n != 2 {
+				t.Errorf("Pending() = %d, want 2", n)
+			}
+			ids := enqueueAll(t, q, "c", "d")
+			if ids[0] != 3 {
+				t.Errorf("ID after torn record = %d, want 3", ids[0])
+			}
+			q.Close()
+
+			q = openTestQueue(t, path, 0)
+			defer q.Close()
+			if got := jobPayloads(receiveJobs(t, q, 4)); !slices.Equal(got, []string{"a", "b", "c", "d"}) {
+				t.Errorf("jobs after reopen = %q, want [a b c d]", got)
+			}
+		})
+	}
+}
+
+// Only the last record may be broken: a broken one in the middle means the log is corrupted
+func TestDurableQueueCorruptedLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+	log := "{\"op\":\"job\",\"id\":1,\"payload\":\"a\"}\nnot json\n{\"op\":\"job\",\"id\":2,\"payload\":\"b\"}\n"
+	if err := os.WriteFile(path, []byte(log), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenDurableQueue[string](path, 0); err == nil || !strings.Contains(err.Error(), "corrupted log") {
+		t.Errorf("OpenDurableQueue = %v, want a corrupted log error", err)
+	}
+}
+
+// Records longer than `bufio.Scanner`'s 64KiB limit
+func TestDurableQueueLargePayload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+	big := strings.Repeat("x", 1<<20)
+	q := openTestQueue(t, path, 0)
+	enqueueAll(t, q, big, "small")
+	q.Close()
+
+	q = openTestQueue(t, path, 0)
+	defer q.Close()
+	jobs := receiveJobs(t, q, 2)
+	if jobs[0].Payload != big || jobs[1].Payload != "small" {
+		t.Errorf("payloads after reopen: %d and %q bytes, want %d and small", len(jobs[0].Payload), jobs[1].Payload, len(big))
+	}
+}
+
+func TestDurableQueueClosed(t *testing.T) {
+	q := openTestQueue(t, filepath.Join(t.TempDir(), "jobs.wal"), 0)
+	q.Close()
+	if _, err := q.Enqueue("a"); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Enqueue after Close = %v, want %v", err, ErrQueueClosed)
+	}
+	if _, ok := <-q.Jobs(); ok {
+		t.Error("Jobs() still open after Close")
+	}
+}
@@ -0,0 +1,266 @@
+// The output of `worker_pools_main` and `wait_groups_main` is a list of "started/finished" lines: it tells what happened,
+// but not really how the jobs overlapped in time, nor how long each worker stayed idle (blocked on a channel).
+//
+// Here we build a small tracer recording spans (a named time interval) per worker, and exporting them:
+//   - as Chrome Trace Event JSON, which can be opened in `chrome://tracing` or https://ui.perfetto.dev
+//     (1 row per worker, 1 box per job, zoomable)
+//   - as an ASCII Gantt chart, printed right in the terminal
+//
+// Fyi, Go also has a built-in (much more detailed) execution tracer: `runtime/trace` + `go tool trace`.
+// It records every goroutine scheduling event, which makes it a lot more verbose than what we need here.
+//
+// See `52-tracing_test.go` for tests of the recorded spans and of the exported JSON.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SpanKind string
+
+const (
+	// Work being done (ex: a job)
+	SpanWork SpanKind = "work"
+	// Time spent blocked (ex: waiting to receive from a channel)
+	SpanWait SpanKind = "wait"
+)
+
+type Span struct {
+	// Row the span is displayed on (ex: "worker 1")
+	Track string
+	Name  string
+	Kind  SpanKind
+	// Relative to the tracer's creation
+	Start, End time.Duration
+	Args       map[string]any
+}
+
+type Tracer struct {
+	start time.Time
+
+	mu    sync.Mutex
+	spans []Span
+	// Tracks in order of first appearance
+	tracks []string
+}
+
+func NewTracer() *Tracer {
+	return &Tracer{start: time.Now()}
+}
+
+// Starts a span, and returns the function ending it (typically deferred, or called right after the traced code).
+// `args` are key/value pairs shown with the span (ex: "job", 3).
+func (t *Tracer) Start(track, name string, kind SpanKind, args ...any) func() {
+	begin := time.Since(t.start)
+
+	return func() {
+		span := Span{Track: track, Name: name, Kind: kind, Start: begin, End: time.Since(t.start)}
+		if len(args) > 0 {
+			span.Args = map[string]any{}
+			for i := 0; i+1 < len(args); i += 2 {
+				span.Args[fmt.Sprint(args[i])] = args[i+1]
+			}
+		}
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if !slices.Contains(t.tracks, track) {
+			t.tracks = append(t.tracks, track)
+		}
+		t.spans = append(t.spans, span)
+	}
+}
+
+// Shorthand for a work span around `fn`
+func (t *Tracer) Do(track, name string, fn func(), args ...any) {
+	end := t.Start(track, name, SpanWork, args...)
+	defer end()
+	fn()
+}
+
+// Receives from `ch`, recording the time spent blocked as a wait span
+func TraceRecv[T any](t *Tracer, track string, ch <-chan T) (T, bool) {
+	end := t.Start(track, "wait", SpanWait)
+	defer end()
+	v, ok := <-ch
+	return v, ok
+}
+
+// Returns a copy of the spans recorded so far
+func (t *Tracer) Spans() []Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Span(nil), t.spans...)
+}
+
+// An event of the Chrome Trace Event format.
+// Spec: https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+type chromeEvent struct {
+	Name string `json:"name"`
+	Cat  string `json:"cat,omitempty"`
+	// Phase: "X" for a complete event (start + duration), "M" for metadata (here, the name of a row)
+	Ph string `json:"ph"`
+	// Timestamps & durations are in microseconds
+	Ts   int64          `json:"ts"`
+	Dur  int64          `json:"dur,omitempty"`
+	Pid  int            `json:"pid"`
+	Tid  int            `json:"tid"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// Writes the spans as Chrome Trace Event JSON: 1 "thread" (row) per track
+func (t *Tracer) WriteChromeTrace(w io.Writer) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	events := make([]chromeEvent, 0, len(t.tracks)+len(t.spans))
+	tids := map[string]int{}
+	for i, track := range t.tracks {
+		tids[track] = i + 1
+		events = append(events, chromeEvent{Name: "thread_name", Ph: "M", Pid: 1, Tid: i + 1, Args: map[string]any{"name": track}})
+	}
+	for _, s := range t.spans {
+		events = append(events, chromeEvent{
+			Name: s.Name,
+			Cat:  string(s.Kind),
+			Ph:   "X",
+			Ts:   s.Start.Microseconds(),
+			Dur:  (s.End - s.Start).Microseconds(),
+			Pid:  1,
+			Tid:  tids[s.Track],
+			Args: s.Args,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]any{"traceEvents": events, "displayTimeUnit": "ms"})
+}
+
+// Writes the spans as an ASCII Gantt chart `width` characters wide, 1 row per track.
+//
+// A work span is drawn with the last digit of its "job" arg (or `#` if none), a wait span with `.`,
+// and nothing recorded with a space.
+func (t *Tracer) WriteGantt(w io.Writer, width int) error {
+	if width < 1 {
+		return fmt.Errorf("gantt chart width must be at least 1, got %d", width)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var total time.Duration
+	labelWidth := 0
+	for _, s := range t.spans {
+		total = max(total, s.End)
+	}
+	for _, track := range t.tracks {
+		labelWidth = max(labelWidth, len(track))
+	}
+	if total == 0 {
+		return nil
+	}
+
+	column := func(d time.Duration) int {
+		return min(int(int64(d)*int64(width)/int64(total)), width-1)
+	}
+
+	for _, track := range t.tracks {
+		row := []byte(strings.Repeat(" ", width))
+		// Waits first, so that work drawn on the same column wins
+		for _, kind := range []SpanKind{SpanWait, SpanWork} {
+			for _, s := range t.spans {
+				if s.Track != track || s.Kind != kind {
+					continue
+				}
+				mark := byte('.')
+				if kind == SpanWork {
+					mark = '#'
+					if job, ok := s.Args["job"]; ok {
+						digits := fmt.Sprint(job)
+						mark = digits[len(digits)-1]
+					}
+				}
+				for c := column(s.Start); c <= column(s.End); c++ {
+					row[c] = mark
+				}
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%-*s |%s|\n", labelWidth, track, row); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%-*s  0%*v\n", labelWidth, "", width-1, total.Round(time.Millisecond))
+	return err
+}
+
+// `workerCh36`, traced: each job is a work span, each wait for the next job a wait span
+func tracedWorkerCh36(t *Tracer, id int, jobs <-chan int, results chan<- int) {
+	track := fmt.Sprint("worker ", id)
+	for {
+		j, ok := TraceRecv(t, track, jobs)
+		if !ok {
+			return
+		}
+		t.Do(track, fmt.Sprint("job ", j), func() { time.Sleep(300 * time.Millisecond) }, "job", j)
+		results <- j * 2
+	}
+}
+
+func tracing_main() {
+	// `worker_pools_main`, traced (with 300ms jobs instead of 1s)
+	tracer := NewTracer()
+	const numJobs = 5
+	jobs := make(chan int, numJobs)
+	results := make(chan int, numJobs)
+	for w := 1; w <= 3; w++ {
+		go tracedWorkerCh36(tracer, w, jobs, results)
+	}
+	for j := 1; j <= numJobs; j++ {
+		jobs <- j
+	}
+	close(jobs)
+	for a := 1; a <= numJobs; a++ {
+		<-results
+	}
+	// Let the workers record their last wait (ended by the channel closing)
+	time.Sleep(10 * time.Millisecond)
+
+	// The 3 workers take jobs 1 to 3, then 2 of them take jobs 4 & 5 while the 3rd one has nothing left to do
+	tracer.WriteGantt(os.Stdout, 60)
+
+	path := filepath.Join(os.TempDir(), "worker-pools-trace.json")
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer f.Close()
+	if err := tracer.WriteChromeTrace(f); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("trace written to", path, "- open it in chrome://tracing or https://ui.perfetto.dev")
+
+	// `wait_groups_main`, traced
+	tracer = NewTracer()
+	var wg sync.WaitGroup
+	for i := 1; i <= 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tracer.Do(fmt.Sprint("goroutine ", i), "workerCh37", func() { time.Sleep(time.Duration(i) * 50 * time.Millisecond) }, "job", i)
+		}()
+	}
+	endWait := tracer.Start("main", "wg.Wait", SpanWait)
+	wg.Wait()
+	endWait()
+	tracer.WriteGantt(os.Stdout, 60)
+}
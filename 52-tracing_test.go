@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteGanttWidth(t *testing.T) {
+	tracer := NewTracer()
+	tracer.Do("worker 1", "job", func() { time.Sleep(time.Millisecond) }, "job", 1)
+
+	for _, width := range []int{-1, 0} {
+		if err := tracer.WriteGantt(io.Discard, width); err == nil {
+			t.Errorf("WriteGantt(width %d) = nil, want an error", width)
+		}
+	}
+	for _, width := range []int{1, 60} {
+		if err := tracer.WriteGantt(io.Discard, width); err != nil {
+			t.Errorf("WriteGantt(width %d) = %v, want nil", width, err)
+		}
+	}
+}
+
+// Records an "outer" span containing an "inner" one on "worker 1", and a wait on "worker 2"
+func nestedTrace() *Tracer {
+	tracer := NewTracer()
+	tracer.Do("worker 1", "outer", func() {
+		time.Sleep(10 * time.Millisecond)
+		tracer.Do("worker 1", "inner", func() { time.Sleep(20 * time.Millisecond) }, "job", 2)
+		time.Sleep(5 * time.Millisecond)
+	}, "job", 1)
+
+	ch := make(chan int)
+	go func() {
+		time.Sleep(15 * time.Millisecond)
+		ch <- 1
+	}()
+	TraceRecv(tracer, "worker 2", ch)
+	return tracer
+}
+
+func TestTracerSpans(t *testing.T) {
+	VerifyNoLeaks(t)
+	spans := nestedTrace().Spans()
+
+	// Recorded when they end: the inner span before the outer one
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want 3: %+v", len(spans), spans)
+	}
+	inner, outer, wait := spans[0], spans[1], spans[2]
+	for _, c := range []struct {
+		span        Span
+		track, name string
+		kind        SpanKind
+		args        map[string]any
+		minDuration time.Duration
+	}{
+		{inner, "worker 1", "inner", SpanWork, map[string]any{"job": 2}, 20 * time.Millisecond},
+		{outer, "worker 1", "outer", SpanWork, map[string]any{"job": 1}, 35 * time.Millisecond},
+		{wait, "worker 2", "wait", SpanWait, nil, 15 * time.Millisecond},
+	} {
+		s := c.span
+		if s.Track != c.track || s.Name != c.name || s.Kind != c.kind || !maps.Equal(s.Args, c.args) {
+			t.Errorf("span = %+v, want %s/%s/%s with args %v", s, c.track, c.name, c.kind, c.args)
+		}
+		if d := s.End - s.Start; d < c.minDuration || d > c.minDuration+time.Second {
+			t.Errorf("span %s lasted %v, want about %v", s.Name, d, c.minDuration)
+		}
+	}
+
+	// The inner span is nested in its parent, after the 10ms it slept first
+	if inner.Start < outer.Start+10*time.Millisecond || inner.End > outer.End {
+		t.Errorf("inner span [%v, %v] not nested in outer span [%v, %v]", inner.Start, inner.End, outer.Start, outer.End)
+	}
+	// The wait starts once the outer span is over
+	if wait.Start < outer.End {
+		t.Errorf("wait span started at %v, before the outer span ended at %v", wait.Start, outer.End)
+	}
+}
+
+func TestWriteChromeTrace(t *testing.T) {
+	VerifyNoLeaks(t)
+	tracer := nestedTrace()
+	var buf bytes.Buffer
+	if err := tracer.WriteChromeTrace(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw struct {
+		TraceEvents     []map[string]any `json:"traceEvents"`
+		DisplayTimeUnit string           `json:"displayTimeUnit"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if raw.DisplayTimeUnit != "ms" {
+		t.Errorf("displayTimeUnit = %q, want ms", raw.DisplayTimeUnit)
+	}
+	// Fields required by the format on every event
+	for _, e := range raw.TraceEvents {
+		for _, field := range []string{"name", "ph", "ts", "pid", "tid"} {
+			if _, ok := e[field]; !ok {
+				t.Errorf("event %v has no %q", e, field)
+			}
+		}
+	}
+
+	var trace struct {
+		TraceEvents []chromeEvent `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &trace); err != nil {
+		t.Fatal(err)
+	}
+	events := trace.TraceEvents
+	if len(events) != 5 {
+		t.Fatalf("%d events, want 2 thread names + 3 spans:\n%s", len(events), buf.String())
+	}
+
+	// 1 row (tid) per track, named by a metadata event
+	tids := map[string]int{}
+	for _, e := range events[:2] {
+		if e.Ph != "M" || e.Name != "thread_name" || e.Pid != 1 {
+			t.Errorf("metadata event = %+v, want ph M, thread_name, pid 1", e)
+		}
+		tids[fmt.Sprint(e.Args["name"])] = e.Tid
+	}
+	if tids["worker 1"] != 1 || tids["worker 2"] != 2 {
+		t.Errorf("tids = %v, want worker 1: 1, worker 2: 2", tids)
+	}
+
+	byName := map[string]chromeEvent{}
+	for i, e := range events[2:] {
+		span := tracer.Spans()[i]
+		want := chromeEvent{
+			Name: span.Name,
+			Cat:  string(span.Kind),
+			Ph:   "X",
+			Ts:   span.Start.Microseconds(),
+			Dur:  (span.End - span.Start).Microseconds(),
+			Pid:  1,
+			Tid:  tids[span.Track],
+		}
+		got := e
+		got.Args = nil
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("event = %+v, want %+v", got, want)
+		}
+		byName[e.Name] = e
+	}
+	// JSON numbers are decoded as float64
+	if job := byName["inner"].Args["job"]; job != 2.0 {
+		t.Errorf("inner job arg = %v, want 2", job)
+	}
+
+	// Chrome nests complete events of the same row by their time range
+	inner, outer := byName["inner"], byName["outer"]
+	if inner.Tid != outer.Tid || inner.Ts < outer.Ts || inner.Ts+inner.Dur > outer.Ts+outer.Dur {
+		t.Errorf("inner event %+v not nested in outer event %+v", inner, outer)
+	}
+	if inner.Dur < (20 * time.Millisecond).Microseconds() {
+		t.Errorf("inner event lasted %dµs, want at least 20ms", inner.Dur)
+	}
+}
@@ -0,0 +1,559 @@
+// `go_routines_main` and `select_main` stress that goroutines run in a non-deterministic order: running the same
+// program twice can give 2 different outputs. Which makes concurrency bugs hard to reproduce: a bug showing up
+// once every 1000 runs might never show up again while we look for it.
+//
+// Here we build a small deterministic simulator: tasks look like goroutines, but only 1 runs at a time, and they
+// give control back to a scheduler at each channel operation, sleep or explicit `Yield`. The scheduler picks
+// the next task to run with a random generator seeded by us:
+// - the same seed always gives the same interleaving (and output)
+// - a failing seed can be replayed as many times as needed, with a trace of every step
+// - trying many seeds explores many interleavings systematically (`Explore`)
+//
+// Time is virtual too: `Sleep` doesn't wait, the clock jumps straight to the next wake-up when all tasks are blocked.
+// Timers are simulated channels (`After`, like `time.After`), so a `Select` can wait for a value OR a timeout.
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Sim struct {
+	seed  uint64
+	rng   *rand.Rand
+	now   time.Duration
+	tasks []*Task
+	// A task sends itself on it when it gives control back to the scheduler (blocked or done)
+	parked chan *Task
+	// Closed to terminate the goroutines of the tasks still blocked at the end (deadlock, panic)
+	kill chan struct{}
+	// Created by `After`, in creation order: the ones firing at the same time fire in that order
+	timers []simTimer
+
+	// Lines printed by the tasks (`Task.Println`)
+	Output []string
+	// Every scheduling step, to understand how a given output came to be
+	Trace []string
+}
+
+type Task struct {
+	sim  *Sim
+	name string
+	wake chan struct{}
+	// Whether the task can be resumed; nil means yes
+	ready func() bool
+	// What the task is blocked on (for the trace & deadlock reports)
+	blockedOn string
+	// Set while in `Sleep`
+	sleeping   bool
+	sleepUntil time.Duration
+	done       bool
+	killed     bool
+	panicked   any
+}
+
+// A pending `After`: `fire` delivers the value on the timer's channel
+type simTimer struct {
+	at   time.Duration
+	fire func()
+}
+
+func NewSim(seed uint64) *Sim {
+	return &Sim{
+		seed: seed,
+		// PCG is a small, fast & seedable random generator: same seed --> same sequence of numbers
+		rng:    rand.New(rand.NewPCG(seed, seed)),
+		parked: make(chan *Task),
+		kill:   make(chan struct{}),
+	}
+}
+
+// Same as the `go` keyword, but for a simulated task. Can be called from a task.
+func (s *Sim) Go(name string, fn func(t *Task)) {
+	t := &Task{sim: s, name: name, wake: make(chan struct{})}
+	s.tasks = append(s.tasks, t)
+
+	go func() {
+		select {
+		case <-t.wake:
+		case <-s.kill:
+			return
+		}
+		defer func() {
+			if t.killed {
+				// Nobody is listening on `parked` anymore
+				return
+			}
+			if r := recover(); r != nil {
+				t.panicked = r
+			}
+			t.done = true
+			s.parked <- t
+		}()
+		fn(t)
+	}()
+}
+
+// Runs the tasks until they all finish.
+// Returns an error if a task panics, or if all remaining tasks are blocked forever (deadlock).
+func (s *Sim) Run() error {
+	defer close(s.kill)
+
+	for {
+		s.fireTimers()
+
+		var runnable []*Task
+		pending := 0
+		for _, t := range s.tasks {
+			if t.done {
+				continue
+			}
+			pending++
+			if t.ready == nil || t.ready() {
+				runnable = append(runnable, t)
+			}
+		}
+		if pending == 0 {
+			return nil
+		}
+
+		if len(runnable) == 0 {
+			// Everyone is blocked: jump to the next timer if any, otherwise nobody will ever be unblocked
+			if next, ok := s.nextWakeUp(); ok {
+				s.now = next
+				continue
+			}
+			return fmt.Errorf("seed %d: deadlock at %v: %s", s.seed, s.now, s.blockedTasks())
+		}
+
+		// The only source of non-determinism, under our control
+		t := runnable[s.rng.IntN(len(runnable))]
+		s.Trace = append(s.Trace, fmt.Sprintf("%6v run %-10s (%d runnable)", s.now, t.name, len(runnable)))
+		t.ready = nil
+		t.wake <- struct{}{}
+		<-s.parked
+
+		if t.panicked != nil {
+			return fmt.Errorf("seed %d: task %s panicked: %v", s.seed, t.name, t.panicked)
+		}
+	}
+}
+
+// Earliest wake-up time of the sleeping tasks & timers
+func (s *Sim) nextWakeUp() (time.Duration, bool) {
+	var next time.Duration
+	found := false
+	for _, t := range s.tasks {
+		if !t.done && t.sleeping && (!found || t.sleepUntil < next) {
+			next, found = t.sleepUntil, true
+		}
+	}
+	for _, timer := range s.timers {
+		if !found || timer.at < next {
+			next, found = timer.at, true
+		}
+	}
+	return next, found
+}
+
+// Fires the timers whose time has come, and forgets them
+func (s *Sim) fireTimers() {
+	s.timers = slices.DeleteFunc(s.timers, func(timer simTimer) bool {
+		if timer.at > s.now {
+			return false
+		}
+		timer.fire()
+		return true
+	})
+}
+
+// Same as `time.After`, in virtual time: returns a channel receiving the time once `d` elapsed.
+// Buffered like the real one, so the timer never blocks if nobody receives (ex: the other case of a `Select` won).
+func (s *Sim) After(d time.Duration) *SimChan[time.Duration] {
+	at := s.now + d
+	ch := NewSimChan[time.Duration](fmt.Sprint("after ", at), 1)
+	s.timers = append(s.timers, simTimer{at: at, fire: func() {
+		ch.buf = append(ch.buf, at)
+		ch.sent++
+	}})
+	return ch
+}
+
+func (s *Sim) blockedTasks() string {
+	var blocked []string
+	for _, t := range s.tasks {
+		if !t.done {
+			blocked = append(blocked, t.name+" on "+t.blockedOn)
+		}
+	}
+	return strings.Join(blocked, ", ")
+}
+
+// Current virtual time
+func (s *Sim) Now() time.Duration {
+	return s.now
+}
+
+// Gives control back to the scheduler until `ready` returns true (checked by the scheduler, between 2 steps)
+func (t *Task) park(what string, ready func() bool) {
+	t.blockedOn = what
+	t.ready = ready
+	t.sim.parked <- t
+
+	select {
+	case <-t.wake:
+		t.blockedOn = ""
+	case <-t.sim.kill:
+		// The simulation is over but we're still blocked: end this goroutine (running its deferred calls)
+		t.killed = true
+		runtime.Goexit()
+	}
+}
+
+// Lets the scheduler run another task (a scheduling point, where a real goroutine could be preempted)
+func (t *Task) Yield() {
+	t.park("yield", nil)
+}
+
+// Virtual sleep: the task is blocked until the clock reaches now + `d`
+func (t *Task) Sleep(d time.Duration) {
+	t.sleeping, t.sleepUntil = true, t.sim.now+d
+	t.park(fmt.Sprint("sleep until ", t.sleepUntil), func() bool { return t.sim.now >= t.sleepUntil })
+	t.sleeping = false
+}
+
+// Like `fmt.Println`, recorded in `Sim.Output` (with the virtual time)
+func (t *Task) Println(args ...any) {
+	line := fmt.Sprintf("%v %s", t.sim.now, fmt.Sprintln(args...))
+	t.sim.Output = append(t.sim.Output, strings.TrimSuffix(line, "\n"))
+}
+
+// A simulated channel, with the same semantics as a Go channel (including unbuffered ones)
+type SimChan[T any] struct {
+	name   string
+	cap    int
+	buf    []T
+	closed bool
+	// Number of values sent & received so far, so that an unbuffered send can wait for ITS value to be received
+	sent, received int
+	// Number of tasks blocked receiving from it (in `Recv` or `Select`), for the send cases of `Select`
+	receivers int
+}
+
+func NewSimChan[T any](name string, capacity int) *SimChan[T] {
+	return &SimChan[T]{name: name, cap: capacity}
+}
+
+func (c *SimChan[T]) Send(t *Task, v T) {
+	// A buffered send waits for room. An unbuffered one can always drop its value (in a 1-slot "hand"), but then
+	// waits for a receiver to take it, which gives the same blocking behaviour as a real unbuffered channel.
+	t.park("send "+c.name, func() bool { return c.closed || len(c.buf) < max(c.cap, 1) })
+	if c.closed {
+		panic("send on closed channel " + c.name)
+	}
+	c.buf = append(c.buf, v)
+	c.sent++
+
+	if c.cap == 0 {
+		mine := c.sent
+		t.park("send "+c.name, func() bool { return c.received >= mine })
+	}
+}
+
+func (c *SimChan[T]) Recv(t *Task) (T, bool) {
+	c.receivers++
+	t.park("recv "+c.name, func() bool { return len(c.buf) > 0 || c.closed })
+	c.receivers--
+	return c.take()
+}
+
+func (c *SimChan[T]) take() (T, bool) {
+	var v T
+	if len(c.buf) == 0 {
+		// Closed & empty
+		return v, false
+	}
+	v, c.buf = c.buf[0], c.buf[1:]
+	c.received++
+	return v, true
+}
+
+func (c *SimChan[T]) Close() {
+	c.closed = true
+}
+
+// A case of `Task.Select`
+type SimCase interface {
+	ready() bool
+	fire()
+	// Called with +1 when the `Select` starts waiting, -1 when it stops
+	waiting(delta int)
+}
+
+type simRecvCase[T any] struct {
+	ch *SimChan[T]
+	fn func(v T, ok bool)
+}
+
+func (c simRecvCase[T]) ready() bool {
+	return len(c.ch.buf) > 0 || c.ch.closed
+}
+
+func (c simRecvCase[T]) fire() {
+	c.fn(c.ch.take())
+}
+
+func (c simRecvCase[T]) waiting(delta int) {
+	c.ch.receivers += delta
+}
+
+// Receive case of a `Select`: `fn` is called with the received value
+func OnRecv[T any](ch *SimChan[T], fn func(v T, ok bool)) SimCase {
+	return simRecvCase[T]{ch, fn}
+}
+
+type simSendCase[T any] struct {
+	ch *SimChan[T]
+	v  T
+	fn func()
+}
+
+// Like a real `select`, sending on a closed channel is chosen (and panics).
+// On an unbuffered channel, a send is only possible when a receiver is waiting for it: the `Select` can't drop its
+// value in the "hand" and wait like `Send` does, as it must not commit to this case before it can complete.
+func (c simSendCase[T]) ready() bool {
+	if c.ch.closed {
+		return true
+	}
+	if c.ch.cap == 0 {
+		return c.ch.receivers > len(c.ch.buf)
+	}
+	return len(c.ch.buf) < c.ch.cap
+}
+
+func (c simSendCase[T]) fire() {
+	if c.ch.closed {
+		panic("send on closed channel " + c.ch.name)
+	}
+	c.ch.buf = append(c.ch.buf, c.v)
+	c.ch.sent++
+	if c.fn != nil {
+		c.fn()
+	}
+}
+
+func (c simSendCase[T]) waiting(int) {}
+
+// Send case of a `Select`: `fn` (optional) is called once `v` is sent.
+//
+// Fyi, on an unbuffered channel, the value is handed to a waiting receiver. If that receiver is itself in a `Select`
+// and picks another of its cases, the value stays in the channel for the next receiver, whereas a real send would
+// have stayed blocked: a simplification, only visible with 2 `Select`s racing on the same unbuffered channel.
+func OnSend[T any](ch *SimChan[T], v T, fn func()) SimCase {
+	return simSendCase[T]{ch, v, fn}
+}
+
+// Like `select`: blocks until one of the cases is ready, and if several are, picks one with the scheduler's
+// random generator (a real `select` picks uniformly at random too).
+// Timeouts are receive cases on a timer: `OnRecv(s.After(time.Second), ...)`
+func (t *Task) Select(cases ...SimCase) {
+	for _, c := range cases {
+		c.waiting(1)
+	}
+	t.park("select", func() bool {
+		return slices.ContainsFunc(cases, SimCase.ready)
+	})
+	for _, c := range cases {
+		c.waiting(-1)
+	}
+
+	var ready []SimCase
+	for _, c := range cases {
+		if c.ready() {
+			ready = append(ready, c)
+		}
+	}
+	ready[t.sim.rng.IntN(len(ready))].fire()
+}
+
+// Runs `scenario` with seeds 0 to `seeds`-1, and groups the seeds by outcome (output, or error).
+// Returns the outcomes in order of first appearance and the seeds giving each of them.
+func Explore(seeds int, scenario func(s *Sim)) (outcomes []string, seedsByOutcome map[string][]uint64) {
+	seedsByOutcome = map[string][]uint64{}
+	for seed := range uint64(seeds) {
+		s := NewSim(seed)
+		scenario(s)
+		err := s.Run()
+		outcome := strings.Join(s.Output, "\n")
+		if err != nil {
+			// The seed is part of the message, remove it to group identical errors together
+			_, msg, _ := strings.Cut(err.Error(), ": ")
+			outcome += "\nERROR: " + msg
+		}
+
+		if _, ok := seedsByOutcome[outcome]; !ok {
+			outcomes = append(outcomes, outcome)
+		}
+		seedsByOutcome[outcome] = append(seedsByOutcome[outcome], seed)
+	}
+	return outcomes, seedsByOutcome
+}
+
+// `go_routines_main`, simulated: "direct" prints first, then the 2 goroutines interleave
+func simGoRoutines(s *Sim) {
+	s.Go("main", func(t *Task) {
+		for i := 0; i < 3; i++ {
+			t.Println("direct :", i)
+		}
+		s.Go("aFct", func(t *Task) {
+			for i := 0; i < 3; i++ {
+				t.Println("goroutine :", i)
+				t.Yield()
+			}
+		})
+		s.Go("anonymous", func(t *Task) {
+			t.Println("going")
+		})
+		t.Sleep(time.Second)
+		t.Println("done")
+	})
+}
+
+// `select_main`, simulated: thanks to the sleeps, the order is always the same (whatever the seed)
+func simSelect(s *Sim) {
+	c1 := NewSimChan[string]("c1", 0)
+	c2 := NewSimChan[string]("c2", 0)
+	s.Go("main", func(t *Task) {
+		s.Go("sender1", func(t *Task) {
+			t.Sleep(1 * time.Second)
+			c1.Send(t, "one")
+		})
+		s.Go("sender2", func(t *Task) {
+			t.Sleep(2 * time.Second)
+			c2.Send(t, "two")
+		})
+		for i := 0; i < 2; i++ {
+			t.Select(
+				OnRecv(c1, func(msg string, _ bool) { t.Println("received from c1", msg) }),
+				OnRecv(c2, func(msg string, _ bool) { t.Println("received from c2", msg) }),
+			)
+		}
+	})
+}
+
+// `timeouts_main`, simulated: the 1st result comes after its timeout, the 2nd one before.
+// No need to wait 5 real seconds: the virtual clock jumps from one timer to the next.
+func simTimeouts(s *Sim) {
+	s.Go("main", func(t *Task) {
+		for i, timeout := range []time.Duration{1 * time.Second, 3 * time.Second} {
+			c := NewSimChan[string](fmt.Sprint("c", i+1), 1)
+			s.Go(fmt.Sprint("worker", i+1), func(t *Task) {
+				t.Sleep(2 * time.Second)
+				c.Send(t, fmt.Sprint("result ", i+1))
+			})
+			t.Select(
+				OnRecv(c, func(res string, _ bool) { t.Println(res) }),
+				OnRecv(s.After(timeout), func(time.Duration, bool) { t.Println("timeout", i+1) }),
+			)
+		}
+	})
+}
+
+// A producer sending jobs until told to stop: a `Select` with a send case & a receive case
+func simProducer(s *Sim) {
+	jobs := NewSimChan[int]("jobs", 0)
+	quit := NewSimChan[bool]("quit", 0)
+	s.Go("producer", func(t *Task) {
+		for i, running := 1, true; running; {
+			t.Select(
+				OnSend(jobs, i, func() { i++ }),
+				OnRecv(quit, func(bool, bool) { running = false }),
+			)
+		}
+		t.Println("producer stopped")
+	})
+	s.Go("consumer", func(t *Task) {
+		for range 3 {
+			job, _ := jobs.Recv(t)
+			t.Println("job", job)
+		}
+		quit.Send(t, true)
+	})
+}
+
+// A buggy counter: `counter++` is a read followed by a write, and another task may run in between (lost update).
+// The bug shows up only for some interleavings.
+func simLostUpdate(s *Sim) {
+	counter := 0
+	done := NewSimChan[bool]("done", 2)
+	for _, name := range []string{"inc1", "inc2"} {
+		s.Go(name, func(t *Task) {
+			v := counter
+			// In a real program: a preemption, or simply another CPU core running at the same time
+			t.Yield()
+			counter = v + 1
+			done.Send(t, true)
+		})
+	}
+	s.Go("main", func(t *Task) {
+		done.Recv(t)
+		done.Recv(t)
+		if counter != 2 {
+			panic(fmt.Sprintf("counter is %d, expected 2", counter))
+		}
+		t.Println("counter:", counter)
+	})
+}
+
+// `channels_main`, simulated: the sender stays blocked on "pong", reported as a deadlock
+func simChannels(s *Sim) {
+	messages := NewSimChan[string]("messages", 0)
+	s.Go("main", func(t *Task) {
+		s.Go("sender", func(t *Task) {
+			messages.Send(t, "ping")
+			messages.Send(t, "pong")
+		})
+		msg, _ := messages.Recv(t)
+		t.Println(msg)
+	})
+}
+
+func printOutcomes(title string, seeds int, scenario func(s *Sim)) {
+	outcomes, seedsByOutcome := Explore(seeds, scenario)
+	fmt.Printf("=== %s: %d distinct outcome(s) over %d seeds\n", title, len(outcomes), seeds)
+	for _, outcome := range outcomes {
+		matching := seedsByOutcome[outcome]
+		fmt.Printf("--- %d seeds (ex: %v)\n%s\n", len(matching), matching[:min(len(matching), 5)], outcome)
+	}
+}
+
+func simulation_main() {
+	printOutcomes("go_routines", 100, simGoRoutines)
+	printOutcomes("select", 100, simSelect)
+	printOutcomes("timeouts", 100, simTimeouts)
+	printOutcomes("producer", 100, simProducer)
+	printOutcomes("lost update", 100, simLostUpdate)
+	printOutcomes("channels", 10, simChannels)
+
+	// Replaying a failing seed: always the same failure, and the trace shows how we got there
+	_, seedsByOutcome := Explore(100, simLostUpdate)
+	for outcome, seeds := range seedsByOutcome {
+		if !strings.Contains(outcome, "ERROR") {
+			continue
+		}
+		for range 3 {
+			s := NewSim(seeds[0])
+			simLostUpdate(s)
+			fmt.Println("replay:", s.Run())
+		}
+		s := NewSim(seeds[0])
+		simLostUpdate(s)
+		s.Run()
+		fmt.Println(strings.Join(s.Trace, "\n"))
+		break
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func runSim(seed uint64, scenario func(s *Sim)) (*Sim, error) {
+	s := NewSim(seed)
+	scenario(s)
+	return s, s.Run()
+}
+
+func TestSimSameSeedSameRun(t *testing.T) {
+	for seed := range uint64(20) {
+		a, errA := runSim(seed, simGoRoutines)
+		b, errB := runSim(seed, simGoRoutines)
+		if errA != nil || errB != nil {
+			t.Fatalf("seed %d: errors %v and %v", seed, errA, errB)
+		}
+		if !slices.Equal(a.Output, b.Output) || !slices.Equal(a.Trace, b.Trace) {
+			t.Errorf("seed %d: 2 runs differ:\n%s\n---\n%s", seed, strings.Join(a.Trace, "\n"), strings.Join(b.Trace, "\n"))
+		}
+	}
+}
+
+func TestSimExploreFindsLostUpdate(t *testing.T) {
+	outcomes, seedsByOutcome := Explore(100, simLostUpdate)
+	if len(outcomes) != 2 {
+		t.Fatalf("got %d outcomes, want 2 (correct & lost update): %q", len(outcomes), outcomes)
+	}
+	for _, outcome := range outcomes {
+		if !strings.Contains(outcome, "counter is 1, expected 2") {
+			continue
+		}
+		// The failing seeds fail every time
+		for _, seed := range seedsByOutcome[outcome] {
+			if _, err := runSim(seed, simLostUpdate); err == nil {
+				t.Errorf("seed %d: replay succeeded, want the lost update", seed)
+			}
+		}
+		return
+	}
+	t.Errorf("lost update not found in %q", outcomes)
+}
+
+func TestSimDeadlock(t *testing.T) {
+	_, err := runSim(0, simChannels)
+	if err == nil || !strings.Contains(err.Error(), "deadlock") || !strings.Contains(err.Error(), "sender on send messages") {
+		t.Errorf("Run() = %v, want a deadlock of the sender", err)
+	}
+}
+
+func TestSimTimeouts(t *testing.T) {
+	outcomes, _ := Explore(50, simTimeouts)
+	want := "1s timeout 1\n3s result 2"
+	if len(outcomes) != 1 || outcomes[0] != want {
+		t.Errorf("outcomes = %q, want only %q", outcomes, want)
+	}
+}
+
+// The clock jumps to the timer when everyone is blocked, and a fired timer never blocks
+func TestSimAfter(t *testing.T) {
+	s, err := runSim(0, func(s *Sim) {
+		s.Go("main", func(t *Task) {
+			// Never received: doesn't keep the simulation running once all tasks are done
+			s.After(time.Hour)
+			at, _ := s.After(5 * time.Second).Recv(t)
+			t.Println("woken up at", at)
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"5s woken up at 5s"}; !slices.Equal(s.Output, want) {
+		t.Errorf("output = %q, want %q", s.Output, want)
+	}
+}
+
+func TestSimSelectSend(t *testing.T) {
+	outcomes, _ := Explore(50, simProducer)
+	want := "0s job 1\n0s job 2\n0s job 3\n0s producer stopped"
+	if len(outcomes) != 1 || outcomes[0] != want {
+		t.Errorf("outcomes = %q, want only %q", outcomes, want)
+	}
+
+	// An unbuffered send case is never ready without a receiver: the timeout wins
+	s, err := runSim(0, func(s *Sim) {
+		c := NewSimChan[int]("c", 0)
+		s.Go("main", func(t *Task) {
+			t.Select(
+				OnSend(c, 1, func() { t.Println("sent") }),
+				OnRecv(s.After(time.Second), func(time.Duration, bool) { t.Println("timeout") }),
+			)
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1s timeout"}; !slices.Equal(s.Output, want) {
+		t.Errorf("output = %q, want %q", s.Output, want)
+	}
+}
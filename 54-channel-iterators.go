@@ -0,0 +1,171 @@
+// `33-range-over-channels` chapter ranges over a channel, `22-iterators` chapter ranges over an `iter.Seq`.
+// Both are sequences of values consumed with `range`, but there is no bridge between the 2:
+// - a channel can't be passed to functions taking an iterator (like `slices.Collect`)
+// - an iterator can't feed goroutines (like the workers of `36-worker-pools` chapter), which receive from channels
+//
+// Here we write adapters in both directions. The tricky part is stopping: an iterator is stopped by its consumer
+// (`break` --> `yield` returns false), whereas a goroutine sending into a channel must be told to stop
+// (here, by cancelling a context), otherwise it stays blocked on its send forever (leak, see `41-goroutine-leaks` chapter).
+// See `54-channel-iterators_test.go` for tests of both ways of stopping.
+package main
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// A value or an error, to carry an `iter.Seq2[T, error]` through a channel (which only carries 1 value per send)
+type Result[T any] struct {
+	Val T
+	Err error
+}
+
+// Iterator over the values received from `ch`, until it's closed.
+//
+// Breaking out of the loop stops receiving, but of course doesn't stop whoever is sending into `ch`
+// (if it's a `ToChan` channel, cancel its context).
+func FromChan[T any](ch <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range ch {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Channel receiving the values of `seq` (sent by a new goroutine), closed once `seq` is exhausted.
+//
+// If the consumer stops receiving before the end, it must cancel `ctx`: the goroutine then stops iterating
+// over `seq` (its `yield` returns false, just like a `break` would) and closes the channel.
+func ToChan[T any](ctx context.Context, seq iter.Seq[T], buf int) <-chan T {
+	ch := make(chan T, buf)
+	go func() {
+		defer close(ch)
+		for v := range seq {
+			select {
+			case ch <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// Same as `FromChan`, for a channel of `Result`s: `for v, err := range FromChan2(ch)`
+func FromChan2[T any](ch <-chan Result[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for r := range ch {
+			if !yield(r.Val, r.Err) {
+				return
+			}
+		}
+	}
+}
+
+// Same as `ToChan`, for an iterator of value/error pairs.
+// If `ctx` is cancelled, a last `Result` carrying `ctx.Err()` is sent (if someone still receives).
+func ToChan2[T any](ctx context.Context, seq iter.Seq2[T, error], buf int) <-chan Result[T] {
+	ch := make(chan Result[T], buf)
+	go func() {
+		defer close(ch)
+		for v, err := range seq {
+			select {
+			case ch <- Result[T]{v, err}:
+			case <-ctx.Done():
+				select {
+				case ch <- Result[T]{Err: ctx.Err()}:
+				default:
+				}
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// Iterator over the first `n` values of `seq` (useful with infinite iterators like `genFib`)
+func Take[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+		i := 0
+		for v := range seq {
+			if !yield(v) {
+				return
+			}
+			i++
+			if i == n {
+				return
+			}
+		}
+	}
+}
+
+func channel_iterators_main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// `genFib` feeding the worker pool of `36-worker-pools` chapter (with shorter jobs)
+	jobs := ToChan(ctx, Take(genFib(), 6), 0)
+	results := make(chan int)
+	done := make(chan bool)
+	for w := 1; w <= 3; w++ {
+		go func() {
+			for j := range jobs {
+				time.Sleep(50 * time.Millisecond)
+				results <- j * 2
+			}
+			done <- true
+		}()
+	}
+	// Closes `results` once all workers are done, so that the range below terminates
+	go func() {
+		for range 3 {
+			<-done
+		}
+		close(results)
+	}()
+
+	// Channel results piped into `slices.Collect` (order depends on the workers)
+	doubled := slices.Collect(FromChan(results))
+	slices.Sort(doubled)
+	fmt.Println("doubled fib:", doubled)
+
+	// Breaking out early: the consumer cancels, the goroutine iterating over the infinite `genFib` stops
+	fibCtx, stopFib := context.WithCancel(ctx)
+	fib := ToChan(fibCtx, genFib(), 0)
+	for n := range FromChan(fib) {
+		if n >= 10 {
+			break
+		}
+		fmt.Println(n)
+	}
+	stopFib()
+	// Drains what the goroutine might have sent before seeing the cancellation; the channel then gets closed
+	for range fib {
+	}
+	fmt.Println("fib goroutine stopped")
+
+	// With errors: parsing numbers, the errors travel through the channel alongside the values
+	parse := func(yield func(int, error) bool) {
+		for _, s := range []string{"1", "2", "three", "4"} {
+			if !yield(strconv.Atoi(s)) {
+				return
+			}
+		}
+	}
+	for n, err := range FromChan2(ToChan2(ctx, parse, 1)) {
+		if err != nil {
+			fmt.Println("error:", err)
+			continue
+		}
+		fmt.Println("parsed", n)
+	}
+}
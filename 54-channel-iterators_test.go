@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Infinite iterator over 0, 1, 2, ..., counting the values pulled out of it
+func countingSeq(pulled *atomic.Int32) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			pulled.Add(1)
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// Receives everything left in `ch`, failing if it isn't closed in time
+func drainChan[T any](t *testing.T, ch <-chan T) []T {
+	t.Helper()
+	var got []T
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return got
+			}
+			got = append(got, v)
+		case <-timeout:
+			t.Fatal("channel not closed")
+		}
+	}
+}
+
+func TestFromChanBreak(t *testing.T) {
+	ch := make(chan int, 5)
+	for i := range 5 {
+		ch <- i
+	}
+	close(ch)
+
+	var got []int
+	for v := range FromChan(ch) {
+		got = append(got, v)
+		if v == 1 {
+			break
+		}
+	}
+	// The values after the `break` are left in the channel
+	if !slices.Equal(got, []int{0, 1}) || len(ch) != 3 {
+		t.Errorf("got %v with %d values left, want [0 1] with 3 left", got, len(ch))
+	}
+	if rest := slices.Collect(FromChan(ch)); !slices.Equal(rest, []int{2, 3, 4}) {
+		t.Errorf("rest = %v, want [2 3 4]", rest)
+	}
+}
+
+func TestFromChan2Break(t *testing.T) {
+	errBad := errors.New("bad")
+	ch := make(chan Result[int], 4)
+	for _, r := range []Result[int]{{1, nil}, {0, errBad}, {3, nil}, {4, nil}} {
+		ch <- r
+	}
+	close(ch)
+
+	var got []Result[int]
+	for v, err := range FromChan2(ch) {
+		got = append(got, Result[int]{v, err})
+		if err != nil {
+			break
+		}
+	}
+	if !slices.Equal(got, []Result[int]{{1, nil}, {0, errBad}}) || len(ch) != 2 {
+		t.Errorf("got %v with %d results left, want [{1 nil} {0 bad}] with 2 left", got, len(ch))
+	}
+}
+
+func TestToChan(t *testing.T) {
+	VerifyNoLeaks(t)
+	got := drainChan(t, ToChan(context.Background(), slices.Values([]int{1, 2, 3}), 0))
+	if !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("ToChan = %v, want [1 2 3]", got)
+	}
+}
+
+// Waits until `seq` was pulled `n` times
+func waitPulled(t *testing.T, pulled *atomic.Int32, n int32) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for pulled.Load() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d values pulled, want %d", pulled.Load(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestToChanCancel(t *testing.T) {
+	VerifyNoLeaks(t)
+	for _, buf := range []int{0, 2} {
+		var pulled atomic.Int32
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := ToChan(ctx, countingSeq(&pulled), buf)
+		for want := range 3 {
+			if v := <-ch; v != want {
+				t.Errorf("buf %d: received %d, want %d", buf, v, want)
+			}
+		}
+		// The buffer is full and the goroutine blocked sending the next value
+		waitPulled(t, &pulled, int32(3+buf+1))
+
+		// Nobody receives: the cancellation is the only way out of its `select`, it stops iterating over the
+		// infinite `seq` and closes the channel
+		cancel()
+		time.Sleep(20 * time.Millisecond)
+		rest := drainChan(t, ch)
+		if want := []int{3, 4}[:buf]; !slices.Equal(rest, want) || pulled.Load() != int32(3+buf+1) {
+			t.Errorf("buf %d: received %v after cancel, %d values pulled, want %v, %d", buf, rest, pulled.Load(), want, 3+buf+1)
+		}
+	}
+}
+
+func TestToChan2(t *testing.T) {
+	VerifyNoLeaks(t)
+	parse := func(yield func(int, error) bool) {
+		for _, s := range []string{"1", "two", "3"} {
+			if !yield(strconv.Atoi(s)) {
+				return
+			}
+		}
+	}
+	got := drainChan(t, ToChan2(context.Background(), parse, 1))
+	if len(got) != 3 || got[0] != (Result[int]{1, nil}) || got[1].Err == nil || got[2] != (Result[int]{3, nil}) {
+		t.Errorf("ToChan2 = %v, want 1, an error, 3", got)
+	}
+}
+
+func TestToChan2Cancel(t *testing.T) {
+	VerifyNoLeaks(t)
+	var pulled atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	values := func(yield func(int, error) bool) {
+		for v := range countingSeq(&pulled) {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+	ch := ToChan2(ctx, values, 0)
+	if r := <-ch; r != (Result[int]{0, nil}) {
+		t.Errorf("received %v, want {0 nil}", r)
+	}
+	waitPulled(t, &pulled, 2)
+
+	// Nobody receives: the goroutine stops without sending the cancellation error
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	if rest := drainChan(t, ch); len(rest) != 0 || pulled.Load() != 2 {
+		t.Errorf("received %v after cancel, %d values pulled, want nothing, 2", rest, pulled.Load())
+	}
+}
+
+func TestTake(t *testing.T) {
+	for _, c := range []struct {
+		n    int
+		want []int
+	}{
+		{-1, nil},
+		{0, nil},
+		{1, []int{0}},
+		{4, []int{0, 1, 2, 3}},
+	} {
+		var pulled atomic.Int32
+		got := slices.Collect(Take(countingSeq(&pulled), c.n))
+		// No value is pulled beyond the n-th one
+		if !slices.Equal(got, c.want) || pulled.Load() != int32(len(c.want)) {
+			t.Errorf("Take(%d) = %v after pulling %d values, want %v", c.n, got, pulled.Load(), c.want)
+		}
+	}
+
+	// Shorter input than n
+	if got := slices.Collect(Take(slices.Values([]int{1, 2}), 5)); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("Take(5) of [1 2] = %v", got)
+	}
+	// And a `break` before n
+	for v := range Take(slices.Values([]int{1, 2, 3}), 3) {
+		if v == 2 {
+			break
+		}
+	}
+}
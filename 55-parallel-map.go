@@ -0,0 +1,125 @@
+// Iterators of `22-iterators` chapter are lazy but strictly sequential: each value is computed (and consumed)
+// one after the other. When computing each value is slow (like the 1s jobs of `workerCh36`), we'd like to
+// compute several of them at once, while keeping the iterator's interface and order.
+//
+// `ParallelMap` applies a function to each value of an iterator in parallel (with a fixed number of workers),
+// and yields the results in the same order as the input:
+// - it only reads ahead a bounded window of values (the input may be infinite, like `genFib`)
+// - a result computed early waits for the ones before it (its slot in the window is kept in order)
+// - a `break` in the consumer's loop cancels the work in progress and stops reading the input
+//
+// See `55-parallel-map_test.go` for tests of the order and of a `break`, and the benchmarks against a sequential loop
+// (`go test -bench ParallelMap`).
+package main
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"time"
+)
+
+// Same as `ParallelMapContext` with a function not needing the context
+func ParallelMap[In, Out any](seq iter.Seq[In], workers int, fn func(In) Out) iter.Seq[Out] {
+	return ParallelMapContext(context.Background(), seq, workers, func(_ context.Context, v In) Out {
+		return fn(v)
+	})
+}
+
+// Iterator over `fn` applied to each value of `seq`, computed by up to `workers` goroutines at once, in input order.
+//
+// At most `2 * workers` values are read ahead: `workers` being computed and `workers` computed waiting to be yielded.
+// `fn`'s context is cancelled when the consumer stops early (or when `ctx` is cancelled, which also ends the iteration).
+//
+// Once the iteration is over, no `fn` call is running anymore. The goroutine reading `seq` might still be blocked
+// inside `seq` though (ex: a `FromChan` channel nobody sends into): we can't interrupt it, so we don't wait for it.
+// It stops at `seq`'s next value, without starting any more work.
+func ParallelMapContext[In, Out any](ctx context.Context, seq iter.Seq[In], workers int, fn func(ctx context.Context, v In) Out) iter.Seq[Out] {
+	workers = max(workers, 1)
+
+	return func(yield func(Out) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		// 1 token per running worker
+		tokens := make(chan struct{}, workers)
+		// Deferred calls run in reverse order: we cancel first, then take all the tokens back, i.e. wait for the
+		// running workers to notice, so that none of them outlives the iteration.
+		// The reader can't start a worker anymore: it would need a token, and its `select` then sees `ctx` cancelled.
+		defer func() {
+			for range workers {
+				tokens <- struct{}{}
+			}
+		}()
+		defer cancel()
+
+		// 1 slot per value read, in input order. Each slot is a channel (buffered 1) receiving that value's result,
+		// so a worker never blocks on it, even if the result is never read.
+		slots := make(chan chan Out, workers)
+
+		go func() {
+			defer close(slots)
+
+			for v := range seq {
+				select {
+				case tokens <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				// Both cases of the `select` above may have been ready: never start a worker once cancelled
+				if ctx.Err() != nil {
+					<-tokens
+					return
+				}
+
+				slot := make(chan Out, 1)
+				go func() {
+					defer func() { <-tokens }()
+					slot <- fn(ctx, v)
+				}()
+
+				select {
+				case slots <- slot:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		for slot := range slots {
+			select {
+			case out := <-slot:
+				if !yield(out) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func parallel_map_main() {
+	// Results are in input order, even though job 1 is the slowest
+	durations := slices.Values([]int{300, 100, 200, 50, 10})
+	sleep := func(ms int) string {
+		time.Sleep(time.Duration(ms) * time.Millisecond)
+		return fmt.Sprint(ms, "ms")
+	}
+	fmt.Println(slices.Collect(ParallelMap(durations, 3, sleep)))
+
+	// Infinite input: only a bounded window of `genFib` is read, and the `break` stops everything
+	start := time.Now()
+	for n := range ParallelMapContext(context.Background(), genFib(), 4, func(ctx context.Context, n int) int {
+		// Work that stops early when cancelled
+		if err := sleepContext(ctx, 100*time.Millisecond); err != nil {
+			return -1
+		}
+		return n * n
+	}) {
+		if n > 1000 {
+			break
+		}
+		fmt.Println(n)
+	}
+	fmt.Println("stopped after", time.Since(start).Round(10*time.Millisecond))
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// The 1s job of `workerCh36` (without the prints)
+func slowDouble(j int) int {
+	time.Sleep(time.Second)
+	return j * 2
+}
+
+// 6 jobs of 1s: ~6s sequentially, ~2s with 3 workers, ~1s with 6
+func BenchmarkParallelMap(b *testing.B) {
+	jobs := []int{1, 2, 3, 4, 5, 6}
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, j := range jobs {
+				slowDouble(j)
+			}
+		}
+	})
+	for _, workers := range []int{3, 6} {
+		b.Run(fmt.Sprintf("%d-workers", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for range ParallelMap(slices.Values(jobs), workers, slowDouble) {
+				}
+			}
+		})
+	}
+}
+
+// Results are yielded in input order, even when the workers finish in the reverse order
+func TestParallelMapOrder(t *testing.T) {
+	VerifyNoLeaks(t)
+	const n = 5
+	var gates [n]chan struct{}
+	for i := range gates {
+		gates[i] = make(chan struct{})
+	}
+	var mu sync.Mutex
+	var finished []int
+	finishedCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(finished)
+	}
+
+	results := make(chan []int)
+	go func() {
+		results <- slices.Collect(ParallelMap(slices.Values([]int{0, 1, 2, 3, 4}), n, func(v int) int {
+			<-gates[v]
+			mu.Lock()
+			finished = append(finished, v)
+			mu.Unlock()
+			return v * 10
+		}))
+	}()
+
+	// Lets the last value finish first, then the one before, etc.
+	for i := n - 1; i >= 0; i-- {
+		close(gates[i])
+		for finishedCount() < n-i {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	got := <-results
+	if !slices.Equal(finished, []int{4, 3, 2, 1, 0}) {
+		t.Errorf("finished in order %v, want [4 3 2 1 0]", finished)
+	}
+	if !slices.Equal(got, []int{0, 10, 20, 30, 40}) {
+		t.Errorf("ParallelMap = %v, want [0 10 20 30 40]", got)
+	}
+}
+
+// Breaking out of the loop cancels the work in progress, which is over once the loop returns
+func TestParallelMapBreak(t *testing.T) {
+	VerifyNoLeaks(t)
+	var pulled, started, cancelled atomic.Int32
+	fn := func(ctx context.Context, v int) int {
+		started.Add(1)
+		if v == 0 {
+			return v
+		}
+		// Never done unless cancelled
+		<-ctx.Done()
+		cancelled.Add(1)
+		return -1
+	}
+
+	for v := range ParallelMapContext(context.Background(), countingSeq(&pulled), 3, fn) {
+		if v != 0 {
+			t.Errorf("yielded %d, want 0", v)
+		}
+		break
+	}
+	// Every call but the 1st one was running until cancelled
+	if s, c := started.Load(), cancelled.Load(); s < 1 || c != s-1 {
+		t.Errorf("%d calls started, %d cancelled, want all but the 1st one cancelled", s, c)
+	}
+	// Only a bounded window of the infinite input was read
+	if p := pulled.Load(); p > 2*3+2 {
+		t.Errorf("%d values pulled, want at most a window of the input", p)
+	}
+}
+
+// A `break` doesn't wait for an input blocked on its next value
+func TestParallelMapBreakBlockedInput(t *testing.T) {
+	VerifyNoLeaks(t)
+	unblock := make(chan struct{})
+	// Only released at the end of the test, so that the goroutine reading it can stop
+	defer close(unblock)
+	seq := func(yield func(int) bool) {
+		if !yield(1) {
+			return
+		}
+		<-unblock
+		yield(2)
+	}
+
+	done := make(chan []int)
+	go func() {
+		var got []int
+		for v := range ParallelMap(iter.Seq[int](seq), 2, func(v int) int { return v * 10 }) {
+			got = append(got, v)
+			break
+		}
+		done <- got
+	}()
+	select {
+	case got := <-done:
+		if !slices.Equal(got, []int{10}) {
+			t.Errorf("got %v, want [10]", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("break blocked on the input")
+	}
+}
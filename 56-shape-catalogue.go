@@ -0,0 +1,302 @@
+// The `geometry` interface of `18-interfaces` chapter only has 2 implementations (`rect2`, `circle`), and both can
+// be built with negative or zero dimensions (`rect2{width: -3}`) without any complaint, giving negative areas.
+//
+// Here we add more shapes, each one with a constructor validating its dimensions and returning an error
+// (of a custom type, see `23-errors` chapter) instead of a nonsensical shape.
+// Fyi, the struct literals (`circle{radius: -1}`) can still be used within the package: Go has no way to force
+// going through a constructor (other than putting the type in its own package with unexported fields).
+//
+// Then, instead of checking a few hand-picked examples, we check properties that must hold for ANY dimensions
+// (ex: "a square has the same area as a rectangle with equal sides"), on lots of random inputs ("property testing"):
+// see `56-shape-catalogue_test.go`, using `testing/quick`.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Matched by every `*ShapeError` with `errors.Is`
+var ErrInvalidShape = errors.New("invalid shape")
+
+type ShapeError struct {
+	Shape  string
+	Reason string
+}
+
+func (e *ShapeError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Shape, e.Reason)
+}
+
+func (e *ShapeError) Is(target error) bool {
+	return target == ErrInvalidShape
+}
+
+// A named dimension of a shape, as checked by `checkPositive`
+type shapeDim struct {
+	name  string
+	value float64
+}
+
+// Returns a `*ShapeError` if one of the dimensions is not strictly positive (NaN and infinity included)
+func checkPositive(shape string, dims ...shapeDim) error {
+	for _, d := range dims {
+		if !(d.value > 0) || math.IsInf(d.value, 1) {
+			return &ShapeError{Shape: shape, Reason: fmt.Sprintf("%s must be a positive number, got %v", d.name, d.value)}
+		}
+	}
+	return nil
+}
+
+// Validating constructors for the shapes of `18-interfaces` chapter
+func newRect2(width, height float64) (*rect2, error) {
+	if err := checkPositive("rectangle", shapeDim{"width", width}, shapeDim{"height", height}); err != nil {
+		return nil, err
+	}
+	return &rect2{width: width, height: height}, nil
+}
+
+func newCircle(radius float64) (circle, error) {
+	if err := checkPositive("circle", shapeDim{"radius", radius}); err != nil {
+		return circle{}, err
+	}
+	return circle{radius: radius}, nil
+}
+
+// Square
+
+type square struct {
+	side float64
+}
+
+func newSquare(side float64) (square, error) {
+	if err := checkPositive("square", shapeDim{"side", side}); err != nil {
+		return square{}, err
+	}
+	return square{side: side}, nil
+}
+
+func (s square) area2() float64 {
+	return s.side * s.side
+}
+
+func (s square) perim2() float64 {
+	return 4 * s.side
+}
+
+// Triangle
+
+type point struct {
+	x, y float64
+}
+
+func (p point) dist(q point) float64 {
+	return math.Hypot(q.x-p.x, q.y-p.y)
+}
+
+type triangle struct {
+	a, b, c float64
+}
+
+func newTriangle(a, b, c float64) (triangle, error) {
+	if err := checkPositive("triangle", shapeDim{"side a", a}, shapeDim{"side b", b}, shapeDim{"side c", c}); err != nil {
+		return triangle{}, err
+	}
+	// Triangle inequality: each side must be shorter than the 2 others together, otherwise they can't meet
+	// (or they meet flat, which gives a "triangle" with no area)
+	if a >= b+c || b >= a+c || c >= a+b {
+		return triangle{}, &ShapeError{Shape: "triangle", Reason: fmt.Sprintf("sides %v, %v, %v violate the triangle inequality", a, b, c)}
+	}
+	return triangle{a: a, b: b, c: c}, nil
+}
+
+func newTriangleFromVertices(p1, p2, p3 point) (triangle, error) {
+	// Collinear vertices: the sides are valid lengths but the triangle is flat
+	if shoelaceArea([]point{p1, p2, p3}) == 0 {
+		return triangle{}, &ShapeError{Shape: "triangle", Reason: "vertices are collinear"}
+	}
+	return newTriangle(p1.dist(p2), p2.dist(p3), p3.dist(p1))
+}
+
+// Heron's formula: area from the 3 sides only
+func (t triangle) area2() float64 {
+	s := (t.a + t.b + t.c) / 2
+	return math.Sqrt(s * (s - t.a) * (s - t.b) * (s - t.c))
+}
+
+func (t triangle) perim2() float64 {
+	return t.a + t.b + t.c
+}
+
+// Regular polygon (all sides & angles equal: equilateral triangle, square, pentagon, hexagon, etc.)
+
+type regularPolygon struct {
+	sides int
+	side  float64
+}
+
+func newRegularPolygon(sides int, side float64) (regularPolygon, error) {
+	if sides < 3 {
+		return regularPolygon{}, &ShapeError{Shape: "regular polygon", Reason: fmt.Sprintf("needs at least 3 sides, got %d", sides)}
+	}
+	if err := checkPositive("regular polygon", shapeDim{"side", side}); err != nil {
+		return regularPolygon{}, err
+	}
+	return regularPolygon{sides: sides, side: side}, nil
+}
+
+func (p regularPolygon) area2() float64 {
+	n := float64(p.sides)
+	return n * p.side * p.side / (4 * math.Tan(math.Pi/n))
+}
+
+func (p regularPolygon) perim2() float64 {
+	return float64(p.sides) * p.side
+}
+
+// Arbitrary simple polygon (edges don't cross each other), given by its vertices in order
+
+type polygon struct {
+	vertices []point
+}
+
+func newPolygon(vertices ...point) (polygon, error) {
+	if len(vertices) < 3 {
+		return polygon{}, &ShapeError{Shape: "polygon", Reason: fmt.Sprintf("needs at least 3 vertices, got %d", len(vertices))}
+	}
+	for _, v := range vertices {
+		if math.IsNaN(v.x) || math.IsNaN(v.y) || math.IsInf(v.x, 0) || math.IsInf(v.y, 0) {
+			return polygon{}, &ShapeError{Shape: "polygon", Reason: fmt.Sprintf("invalid vertex %v", v)}
+		}
+	}
+	if shoelaceArea(vertices) == 0 {
+		return polygon{}, &ShapeError{Shape: "polygon", Reason: "area is zero"}
+	}
+
+	// Every edge against every other edge, except its 2 neighbours (they share a vertex, so they always "touch")
+	n := len(vertices)
+	for i := 0; i < n; i++ {
+		for j := i + 2; j < n; j++ {
+			if i == 0 && j == n-1 {
+				continue
+			}
+			if segmentsIntersect(vertices[i], vertices[(i+1)%n], vertices[j], vertices[(j+1)%n]) {
+				return polygon{}, &ShapeError{Shape: "polygon", Reason: fmt.Sprintf("edges %d and %d cross each other", i, j)}
+			}
+		}
+	}
+
+	// Copy, so that the caller modifying its slice afterwards doesn't modify our polygon
+	return polygon{vertices: append([]point(nil), vertices...)}, nil
+}
+
+// Shoelace formula: sum of the cross products of consecutive vertices (the sign depends on the vertices' direction)
+func shoelaceArea(vertices []point) float64 {
+	sum := 0.0
+	for i, p := range vertices {
+		q := vertices[(i+1)%len(vertices)]
+		sum += p.x*q.y - q.x*p.y
+	}
+	return math.Abs(sum) / 2
+}
+
+// Sign of the cross product of (b - a) and (c - a): > 0 if a, b, c turn left, < 0 if right, 0 if collinear
+func orientation(a, b, c point) float64 {
+	return (b.x-a.x)*(c.y-a.y) - (b.y-a.y)*(c.x-a.x)
+}
+
+// Whether `p` (known to be collinear with segment [a, b]) lies within its bounding box
+func onSegment(a, b, p point) bool {
+	return min(a.x, b.x) <= p.x && p.x <= max(a.x, b.x) && min(a.y, b.y) <= p.y && p.y <= max(a.y, b.y)
+}
+
+// Whether segments [p1, p2] and [q1, q2] share at least 1 point
+func segmentsIntersect(p1, p2, q1, q2 point) bool {
+	d1, d2 := orientation(q1, q2, p1), orientation(q1, q2, p2)
+	d3, d4 := orientation(p1, p2, q1), orientation(p1, p2, q2)
+
+	// Each segment's ends are on both sides of the other one
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	// Touching cases (an end lying on the other segment)
+	return (d1 == 0 && onSegment(q1, q2, p1)) || (d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) || (d4 == 0 && onSegment(p1, p2, q2))
+}
+
+func (p polygon) area2() float64 {
+	return shoelaceArea(p.vertices)
+}
+
+func (p polygon) perim2() float64 {
+	sum := 0.0
+	for i, v := range p.vertices {
+		sum += v.dist(p.vertices[(i+1)%len(p.vertices)])
+	}
+	return sum
+}
+
+// Ellipse, given by its 2 semi-axes
+
+type ellipse struct {
+	a, b float64
+}
+
+func newEllipse(a, b float64) (ellipse, error) {
+	if err := checkPositive("ellipse", shapeDim{"semi-axis a", a}, shapeDim{"semi-axis b", b}); err != nil {
+		return ellipse{}, err
+	}
+	return ellipse{a: a, b: b}, nil
+}
+
+func (e ellipse) area2() float64 {
+	return math.Pi * e.a * e.b
+}
+
+// There is no exact closed formula for an ellipse's perimeter. Ramanujan's 2nd approximation is very precise
+// (exact for a circle, and the error stays below 0.005% even for very flat ellipses).
+func (e ellipse) perim2() float64 {
+	h := (e.a - e.b) * (e.a - e.b) / ((e.a + e.b) * (e.a + e.b))
+	return math.Pi * (e.a + e.b) * (1 + 3*h/(10+math.Sqrt(4-3*h)))
+}
+
+// Relative comparison: float computations accumulate rounding errors, so `==` is too strict
+func almostEqual(x, y float64) bool {
+	return math.Abs(x-y) <= 1e-9*max(math.Abs(x), math.Abs(y), 1)
+}
+
+func shape_catalogue_main() {
+	shapes := []geometry{}
+	add := func(g geometry, err error) {
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		shapes = append(shapes, g)
+	}
+	add(newSquare(2))
+	add(newTriangle(3, 4, 5))
+	add(newTriangleFromVertices(point{0, 0}, point{4, 0}, point{0, 3}))
+	add(newRegularPolygon(6, 1))
+	add(newPolygon(point{0, 0}, point{4, 0}, point{4, 4}, point{2, 2}, point{0, 4}))
+	add(newEllipse(5, 3))
+	for _, g := range shapes {
+		fmt.Printf("%-60s area %8.3f  perimeter %8.3f\n", fmt.Sprintf("%T%v", g, g), g.area2(), g.perim2())
+	}
+
+	// Invalid shapes
+	_, err := newRect2(-3, 4)
+	fmt.Println(err, "- is ErrInvalidShape:", errors.Is(err, ErrInvalidShape))
+	add(newTriangle(1, 2, 10))
+	add(newTriangleFromVertices(point{0, 0}, point{1, 1}, point{2, 2}))
+	add(newRegularPolygon(2, 1))
+	// An "hourglass": edges 0 and 2 cross
+	add(newPolygon(point{0, 0}, point{4, 4}, point{4, 0}, point{0, 2}))
+	add(newEllipse(1, math.NaN()))
+
+	var shapeErr *ShapeError
+	if _, err := newSquare(0); errors.As(err, &shapeErr) {
+		fmt.Println("shape:", shapeErr.Shape, "- reason:", shapeErr.Reason)
+	}
+}
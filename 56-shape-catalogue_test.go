@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// Random dimension between 0.001 and 1000 (instead of `quick`'s default floats, which can be negative or huge)
+type dimension float64
+
+func (dimension) Generate(r *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(dimension(0.001 + r.Float64()*1000))
+}
+
+// Runs `f` (a function returning whether the property holds) on 1000 random inputs
+func checkProperty(t *testing.T, f any) {
+	t.Helper()
+	if err := quick.Check(f, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Error(err)
+	}
+}
+
+// Isoperimetric inequality: of all shapes with a given perimeter, the circle has the largest area (4πA <= P²)
+func isoperimetric(g geometry) bool {
+	return 4*math.Pi*g.area2() <= g.perim2()*g.perim2()*(1+1e-9)
+}
+
+func TestPropertySquareIsRectangle(t *testing.T) {
+	checkProperty(t, func(s dimension) bool {
+		sq, _ := newSquare(float64(s))
+		r, _ := newRect2(float64(s), float64(s))
+		return almostEqual(sq.area2(), r.area2()) && almostEqual(sq.perim2(), r.perim2())
+	})
+}
+
+func TestPropertyRegularPolygonOf4SidesIsSquare(t *testing.T) {
+	checkProperty(t, func(s dimension) bool {
+		p, _ := newRegularPolygon(4, float64(s))
+		sq, _ := newSquare(float64(s))
+		return almostEqual(p.area2(), sq.area2()) && almostEqual(p.perim2(), sq.perim2())
+	})
+}
+
+func TestPropertyEllipseWithEqualAxesIsCircle(t *testing.T) {
+	checkProperty(t, func(r dimension) bool {
+		e, _ := newEllipse(float64(r), float64(r))
+		c, _ := newCircle(float64(r))
+		return almostEqual(e.area2(), c.area2()) && almostEqual(e.perim2(), c.perim2())
+	})
+}
+
+func TestPropertyHeronIsShoelace(t *testing.T) {
+	checkProperty(t, func(x1, y1, x2, y2, x3, y3 dimension) bool {
+		vertices := []point{{float64(x1), float64(y1)}, {float64(x2), float64(y2)}, {float64(x3), float64(y3)}}
+		tri, err := newTriangleFromVertices(vertices[0], vertices[1], vertices[2])
+		if err != nil {
+			return true
+		}
+		// Heron's formula loses precision on very flat triangles, hence the looser comparison
+		return math.Abs(tri.area2()-shoelaceArea(vertices)) <= 1e-6*max(tri.perim2()*tri.perim2(), 1)
+	})
+}
+
+// The shoelace area of an arbitrary polygon matches the closed-form area of the same shape:
+// a rectangle, and a regular polygon (placed and rotated anywhere, as the area doesn't depend on it)
+func TestPropertyPolygonIsShoelace(t *testing.T) {
+	checkProperty(t, func(x, y, w, h dimension) bool {
+		x0, y0, x1, y1 := float64(x), float64(y), float64(x+w), float64(y+h)
+		p, err := newPolygon(point{x0, y0}, point{x1, y0}, point{x1, y1}, point{x0, y1})
+		r, _ := newRect2(float64(w), float64(h))
+		return err == nil && almostEqual(p.area2(), r.area2()) && almostEqual(p.perim2(), r.perim2())
+	})
+
+	checkProperty(t, func(x, y, side dimension, n uint8, angle float64) bool {
+		reg, _ := newRegularPolygon(int(n%50)+3, float64(side))
+		// Vertices on the circumscribed circle, of radius side / (2 sin(π/n))
+		sides := float64(reg.sides)
+		radius := reg.side / (2 * math.Sin(math.Pi/sides))
+		vertices := make([]point, reg.sides)
+		for i := range vertices {
+			theta := math.Mod(angle, 2*math.Pi) + 2*math.Pi*float64(i)/sides
+			vertices[i] = point{float64(x) + radius*math.Cos(theta), float64(y) + radius*math.Sin(theta)}
+		}
+		p, err := newPolygon(vertices...)
+		return err == nil && almostEqual(p.area2(), reg.area2()) && almostEqual(p.perim2(), reg.perim2())
+	})
+}
+
+func TestPropertyScaling(t *testing.T) {
+	// Scaling by k: area * k², perimeter * k
+	checkProperty(t, func(a, b, k dimension) bool {
+		e1, _ := newEllipse(float64(a), float64(b))
+		e2, _ := newEllipse(float64(a*k), float64(b*k))
+		return almostEqual(e2.area2(), e1.area2()*float64(k*k)) && almostEqual(e2.perim2(), e1.perim2()*float64(k))
+	})
+}
+
+func TestPropertyIsoperimetricInequality(t *testing.T) {
+	checkProperty(t, func(a, b dimension, n uint8) bool {
+		e, _ := newEllipse(float64(a), float64(b))
+		p, _ := newRegularPolygon(int(n%50)+3, float64(a))
+		r, _ := newRect2(float64(a), float64(b))
+		return isoperimetric(e) && isoperimetric(p) && isoperimetric(r)
+	})
+}
+
+func TestPropertyNonPositiveDimensionsRejected(t *testing.T) {
+	checkProperty(t, func(v float64) bool {
+		v = -math.Abs(v)
+		_, err := newEllipse(v, 1)
+		return errors.Is(err, ErrInvalidShape)
+	})
+}
+
+func TestInvalidShapes(t *testing.T) {
+	for name, err := range map[string]error{
+		"negative rectangle":  second(newRect2(-3, 4)),
+		"triangle inequality": second(newTriangle(1, 2, 10)),
+		"aligned vertices":    second(newTriangleFromVertices(point{0, 0}, point{1, 1}, point{2, 2})),
+		"2-sided polygon":     second(newRegularPolygon(2, 1)),
+		// Edges 0 and 2 cross
+		"hourglass":    second(newPolygon(point{0, 0}, point{4, 4}, point{4, 0}, point{0, 2})),
+		"NaN ellipse":  second(newEllipse(1, math.NaN())),
+		"empty square": second(newSquare(0)),
+	} {
+		var shapeErr *ShapeError
+		if !errors.Is(err, ErrInvalidShape) || !errors.As(err, &shapeErr) {
+			t.Errorf("%s: err = %v, want a *ShapeError", name, err)
+		}
+	}
+}
+
+// The error of a constructor's results
+func second[T any](_ T, err error) error {
+	return err
+}
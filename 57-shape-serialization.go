@@ -0,0 +1,398 @@
+// All the `geometry` values so far are built in code. To read shapes from a file (a catalogue of shapes, say),
+// we need to encode them as data and decode them back.
+//
+// JSON alone doesn't work here:
+//   - the fields of our shapes are unexported, and `encoding/json` ignores them (`{}` for every shape)
+//   - an interface value doesn't say which concrete type to decode into: `json.Unmarshal` into a `geometry`
+//     fails, as the decoder can't guess whether `{"radius": 5}` is a `circle` or something else
+//
+// So every shape is written with a "type" tag: `{"type":"circle","radius":5}`, and a registry maps each tag
+// to a function decoding the rest of the object (through the validating constructors of `56-shape-catalogue`
+// chapter, so that a file can't give us a circle with a negative radius).
+//
+// See `57-shape-serialization_test.go` for round trips of every shape type and the decoding errors.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+)
+
+// Returned (wrapped) when decoding a shape whose "type" isn't registered
+var ErrUnknownShape = errors.New("unknown shape type")
+
+type UnknownShapeError struct {
+	Type string
+}
+
+func (e *UnknownShapeError) Error() string {
+	return fmt.Sprintf("unknown shape type %q (known types: %v)", e.Type, registeredShapes())
+}
+
+func (e *UnknownShapeError) Is(target error) bool {
+	return target == ErrUnknownShape
+}
+
+// Decodes the fields of a shape (the object without its "type" tag)
+type shapeDecoder func(data []byte) (geometry, error)
+
+var shapeRegistry = map[string]shapeDecoder{}
+
+// Registers shape type `name`: its fields are decoded into a `W` (a struct with exported & tagged fields),
+// then turned into a shape by `build` (which should validate them).
+//
+// Fyi, a generic function can't be a method (methods can't have their own type parameters), hence the function.
+func RegisterShape[W any](name string, build func(w W) (geometry, error)) {
+	if _, ok := shapeRegistry[name]; ok {
+		panic("shape type registered twice: " + name)
+	}
+	shapeRegistry[name] = func(data []byte) (geometry, error) {
+		var w W
+		dec := json.NewDecoder(bytes.NewReader(data))
+		// A typo in a field name (`"radus"`) is an error, instead of a silently missing (zero) field
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&w); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", name, err)
+		}
+		return build(w)
+	}
+}
+
+func registeredShapes() []string {
+	names := make([]string, 0, len(shapeRegistry))
+	for name := range shapeRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Encodes `fields` (a struct with exported & tagged fields) as a JSON object, with the "type" tag first
+func marshalShape(name string, fields any) ([]byte, error) {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	tag, _ := json.Marshal(name)
+	out := append([]byte(`{"type":`), tag...)
+	if string(data) != "{}" {
+		// `data` is `{...}`: we reuse it without its opening brace
+		out = append(out, ',')
+		data = data[1:]
+	} else {
+		data = []byte("}")
+	}
+	return append(out, data...), nil
+}
+
+// Decodes a shape of any registered type
+func DecodeShape(data []byte) (geometry, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var name string
+	if err := json.Unmarshal(fields["type"], &name); err != nil || name == "" {
+		return nil, errors.New(`shape has no "type"`)
+	}
+	decode, ok := shapeRegistry[name]
+	if !ok {
+		return nil, &UnknownShapeError{Type: name}
+	}
+
+	// The remaining fields are the shape's own
+	delete(fields, "type")
+	rest, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return decode(rest)
+}
+
+// Decodes a shape of a known concrete type (used by the `UnmarshalJSON` methods below)
+func decodeShapeInto[S geometry](data []byte, dst *S) error {
+	g, err := DecodeShape(data)
+	if err != nil {
+		return err
+	}
+	s, ok := g.(S)
+	if !ok {
+		return fmt.Errorf("expected a %T, got a %T", *dst, g)
+	}
+	*dst = s
+	return nil
+}
+
+// A list of shapes of any types: `json.Unmarshal` into a `[]geometry` can't work, but into a `ShapeList` it does
+// (also usable as a field of a bigger struct).
+type ShapeList []geometry
+
+func (l ShapeList) MarshalJSON() ([]byte, error) {
+	// `[]geometry` is encoded just fine: each element's `MarshalJSON` is called
+	return json.Marshal([]geometry(l))
+}
+
+func (l *ShapeList) UnmarshalJSON(data []byte) error {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return err
+	}
+	shapes := make(ShapeList, 0, len(raws))
+	for i, raw := range raws {
+		g, err := DecodeShape(raw)
+		if err != nil {
+			return fmt.Errorf("shape #%d: %w", i, err)
+		}
+		shapes = append(shapes, g)
+	}
+	*l = shapes
+	return nil
+}
+
+// Reads a JSON file holding a list of shapes
+func LoadShapes(path string) ([]geometry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var shapes ShapeList
+	if err := json.Unmarshal(data, &shapes); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return shapes, nil
+}
+
+// Writes shapes to a JSON file (1 shape per line)
+func SaveShapes(path string, shapes []geometry) error {
+	var buf bytes.Buffer
+	buf.WriteString("[\n")
+	for i, g := range shapes {
+		data, err := json.Marshal(g)
+		if err != nil {
+			return err
+		}
+		buf.WriteString("  ")
+		buf.Write(data)
+		if i < len(shapes)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("]\n")
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// Fields of each shape type
+
+type rectFields struct {
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+type circleFields struct {
+	Radius float64 `json:"radius"`
+}
+
+type squareFields struct {
+	Side float64 `json:"side"`
+}
+
+// A triangle is written with its sides, but can also be read from its vertices
+type triangleFields struct {
+	A        float64 `json:"a,omitempty"`
+	B        float64 `json:"b,omitempty"`
+	C        float64 `json:"c,omitempty"`
+	Vertices []point `json:"vertices,omitempty"`
+}
+
+type regularPolygonFields struct {
+	Sides int     `json:"sides"`
+	Side  float64 `json:"side"`
+}
+
+type polygonFields struct {
+	Vertices []point `json:"vertices"`
+}
+
+type ellipseFields struct {
+	A float64 `json:"a"`
+	B float64 `json:"b"`
+}
+
+// Turns a constructor's result into a `build` function's: on error, the shape must be a nil `geometry`,
+// not a `geometry` holding the zero value (or a nil `*rect2`) returned along with the error
+func asGeometry[S geometry](s S, err error) (geometry, error) {
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// `init` functions run automatically (before `main`), once all package-level variables are initialized
+func init() {
+	// `rect2` only implements `geometry` through a pointer (see `18-interfaces` chapter), so it's decoded as a `*rect2`
+	RegisterShape("rectangle", func(f rectFields) (geometry, error) { return asGeometry(newRect2(f.Width, f.Height)) })
+	RegisterShape("circle", func(f circleFields) (geometry, error) { return asGeometry(newCircle(f.Radius)) })
+	RegisterShape("square", func(f squareFields) (geometry, error) { return asGeometry(newSquare(f.Side)) })
+	RegisterShape("triangle", func(f triangleFields) (geometry, error) {
+		if f.Vertices == nil {
+			return asGeometry(newTriangle(f.A, f.B, f.C))
+		}
+		// Both forms given: they may not describe the same triangle, we don't pick one for the file's author
+		if f.A != 0 || f.B != 0 || f.C != 0 {
+			return nil, &ShapeError{Shape: "triangle", Reason: "ambiguous: give either its vertices or its sides, not both"}
+		}
+		if len(f.Vertices) != 3 {
+			return nil, &ShapeError{Shape: "triangle", Reason: fmt.Sprintf("needs 3 vertices, got %d", len(f.Vertices))}
+		}
+		return asGeometry(newTriangleFromVertices(f.Vertices[0], f.Vertices[1], f.Vertices[2]))
+	})
+	RegisterShape("regular_polygon", func(f regularPolygonFields) (geometry, error) {
+		return asGeometry(newRegularPolygon(f.Sides, f.Side))
+	})
+	RegisterShape("polygon", func(f polygonFields) (geometry, error) { return asGeometry(newPolygon(f.Vertices...)) })
+	RegisterShape("ellipse", func(f ellipseFields) (geometry, error) { return asGeometry(newEllipse(f.A, f.B)) })
+}
+
+// A point is written `[x, y]`
+
+func (p point) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]float64{p.x, p.y})
+}
+
+func (p *point) UnmarshalJSON(data []byte) error {
+	var xy [2]float64
+	if err := json.Unmarshal(data, &xy); err != nil {
+		return err
+	}
+	p.x, p.y = xy[0], xy[1]
+	return nil
+}
+
+// `MarshalJSON` methods have a value receiver, so that they work for values and pointers alike.
+// `UnmarshalJSON` methods need a pointer receiver, to modify the shape being decoded.
+
+func (r rect2) MarshalJSON() ([]byte, error) {
+	return marshalShape("rectangle", rectFields{r.width, r.height})
+}
+
+func (r *rect2) UnmarshalJSON(data []byte) error {
+	var decoded *rect2
+	if err := decodeShapeInto(data, &decoded); err != nil {
+		return err
+	}
+	*r = *decoded
+	return nil
+}
+
+func (c circle) MarshalJSON() ([]byte, error) {
+	return marshalShape("circle", circleFields{c.radius})
+}
+
+func (c *circle) UnmarshalJSON(data []byte) error {
+	return decodeShapeInto(data, c)
+}
+
+func (s square) MarshalJSON() ([]byte, error) {
+	return marshalShape("square", squareFields{s.side})
+}
+
+func (s *square) UnmarshalJSON(data []byte) error {
+	return decodeShapeInto(data, s)
+}
+
+func (t triangle) MarshalJSON() ([]byte, error) {
+	return marshalShape("triangle", triangleFields{A: t.a, B: t.b, C: t.c})
+}
+
+func (t *triangle) UnmarshalJSON(data []byte) error {
+	return decodeShapeInto(data, t)
+}
+
+func (p regularPolygon) MarshalJSON() ([]byte, error) {
+	return marshalShape("regular_polygon", regularPolygonFields{p.sides, p.side})
+}
+
+func (p *regularPolygon) UnmarshalJSON(data []byte) error {
+	return decodeShapeInto(data, p)
+}
+
+func (p polygon) MarshalJSON() ([]byte, error) {
+	return marshalShape("polygon", polygonFields{p.vertices})
+}
+
+func (p *polygon) UnmarshalJSON(data []byte) error {
+	return decodeShapeInto(data, p)
+}
+
+func (e ellipse) MarshalJSON() ([]byte, error) {
+	return marshalShape("ellipse", ellipseFields{e.a, e.b})
+}
+
+func (e *ellipse) UnmarshalJSON(data []byte) error {
+	return decodeShapeInto(data, e)
+}
+
+func shape_serialization_main() {
+	catalogue := []byte(`[
+		{"type": "rectangle", "width": 3, "height": 4},
+		{"type": "circle", "radius": 5},
+		{"type": "square", "side": 2},
+		{"type": "triangle", "vertices": [[0, 0], [4, 0], [0, 3]]},
+		{"type": "regular_polygon", "sides": 6, "side": 1},
+		{"type": "polygon", "vertices": [[0, 0], [4, 0], [4, 4], [2, 2], [0, 4]]},
+		{"type": "ellipse", "a": 5, "b": 3}
+	]`)
+
+	var shapes ShapeList
+	if err := json.Unmarshal(catalogue, &shapes); err != nil {
+		panic(err)
+	}
+	for _, g := range shapes {
+		measure(g)
+	}
+
+	// Round trip through a file
+	f, err := os.CreateTemp("", "shapes-*.json")
+	if err != nil {
+		panic(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	if err := SaveShapes(f.Name(), shapes); err != nil {
+		panic(err)
+	}
+	data, _ := os.ReadFile(f.Name())
+	fmt.Print(string(data))
+	loaded, err := LoadShapes(f.Name())
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("round trip equal:", reflect.DeepEqual([]geometry(shapes), loaded))
+
+	// Concrete types can be decoded directly too (the "type" tag must match)
+	var c circle
+	fmt.Println(json.Unmarshal([]byte(`{"type":"circle","radius":2.5}`), &c), c)
+	fmt.Println(json.Unmarshal([]byte(`{"type":"square","side":2}`), &c))
+
+	// Invalid data
+	for _, bad := range []string{
+		`[{"type": "circle", "radius": 1}, {"type": "hexagon", "side": 1}]`,
+		`[{"type": "circle", "radus": 1}]`,
+		`[{"type": "circle", "radius": -1}]`,
+		`[{"radius": 1}]`,
+		`[{"type": "triangle", "vertices": [[0, 0], [1, 1]]}]`,
+	} {
+		var shapes ShapeList
+		err := json.Unmarshal([]byte(bad), &shapes)
+		fmt.Println(err)
+		fmt.Println("  unknown:", errors.Is(err, ErrUnknownShape), "- invalid:", errors.Is(err, ErrInvalidShape))
+	}
+
+	fmt.Println("registered:", registeredShapes())
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// One valid shape of every registered type
+func everyShapeType() []geometry {
+	return []geometry{
+		mustShape(newRect2(3, 4)),
+		mustShape(newCircle(5)),
+		mustShape(newSquare(2)),
+		mustShape(newTriangle(3, 4, 5)),
+		mustShape(newRegularPolygon(6, 1)),
+		mustShape(newPolygon(point{0, 0}, point{4, 0}, point{4, 4}, point{2, 2}, point{0, 4})),
+		mustShape(newEllipse(5, 3)),
+	}
+}
+
+// For constructor calls with dimensions known to be valid
+func mustShape[S geometry](s S, err error) geometry {
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func TestShapeRoundTrip(t *testing.T) {
+	var types []string
+	for _, g := range everyShapeType() {
+		data, err := json.Marshal(g)
+		if err != nil {
+			t.Fatalf("Marshal(%v): %v", g, err)
+		}
+		var tag struct{ Type string }
+		json.Unmarshal(data, &tag)
+		types = append(types, tag.Type)
+
+		decoded, err := DecodeShape(data)
+		if err != nil || !reflect.DeepEqual(decoded, g) {
+			t.Errorf("DecodeShape(%s) = %#v, %v, want %#v", data, decoded, err, g)
+		}
+	}
+	slices.Sort(types)
+	if registered := registeredShapes(); !slices.Equal(types, registered) {
+		t.Errorf("round trips of %v, want every registered type %v", types, registered)
+	}
+
+	// Through a file
+	path := filepath.Join(t.TempDir(), "shapes.json")
+	if err := SaveShapes(path, everyShapeType()); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadShapes(path)
+	if err != nil || !reflect.DeepEqual(loaded, everyShapeType()) {
+		t.Errorf("LoadShapes = %v, %v, want %v", loaded, err, everyShapeType())
+	}
+}
+
+func TestDecodeTriangleFromVertices(t *testing.T) {
+	g, err := DecodeShape([]byte(`{"type": "triangle", "vertices": [[0, 0], [4, 0], [0, 3]]}`))
+	want, _ := newTriangleFromVertices(point{0, 0}, point{4, 0}, point{0, 3})
+	if err != nil || g != want {
+		t.Fatalf("DecodeShape = %v, %v, want %v", g, err, want)
+	}
+	if !almostEqual(g.area2(), 6) || !almostEqual(g.perim2(), 12) {
+		t.Errorf("area %v, perimeter %v, want 6, 12", g.area2(), g.perim2())
+	}
+}
+
+func TestDecodeShapeErrors(t *testing.T) {
+	for _, c := range []struct {
+		name, data string
+		// Matched with `errors.Is`, if not nil
+		target error
+		// Contained in the error message
+		msg string
+	}{
+		{"unknown type", `{"type": "hexagon", "side": 1}`, ErrUnknownShape, `unknown shape type "hexagon"`},
+		{"no type", `{"radius": 1}`, nil, `shape has no "type"`},
+		{"not an object", `[1, 2]`, nil, "cannot unmarshal array"},
+		{"unknown field", `{"type": "circle", "radus": 1}`, nil, `unknown field "radus"`},
+		{"wrong field type", `{"type": "circle", "radius": "1"}`, nil, "decoding circle"},
+		{"invalid rectangle", `{"type": "rectangle", "width": -3, "height": 4}`, ErrInvalidShape, "width must be a positive number"},
+		{"invalid circle", `{"type": "circle", "radius": 0}`, ErrInvalidShape, "radius must be a positive number"},
+		{"missing field", `{"type": "square"}`, ErrInvalidShape, "side must be a positive number"},
+		{"invalid triangle sides", `{"type": "triangle", "a": 1, "b": 1, "c": 5}`, ErrInvalidShape, "invalid triangle"},
+		{"2 triangle vertices", `{"type": "triangle", "vertices": [[0, 0], [1, 1]]}`, ErrInvalidShape, "needs 3 vertices, got 2"},
+		{"flat triangle", `{"type": "triangle", "vertices": [[0, 0], [1, 1], [2, 2]]}`, ErrInvalidShape, "invalid triangle"},
+		{"vertices and sides", `{"type": "triangle", "a": 3, "b": 4, "c": 5, "vertices": [[0, 0], [4, 0], [0, 3]]}`, ErrInvalidShape, "ambiguous"},
+		{"invalid regular polygon", `{"type": "regular_polygon", "sides": 2, "side": 1}`, ErrInvalidShape, "needs at least 3 sides"},
+		{"invalid polygon", `{"type": "polygon", "vertices": [[0, 0], [1, 0]]}`, ErrInvalidShape, "needs at least 3 vertices"},
+		{"invalid ellipse", `{"type": "ellipse", "a": 1}`, ErrInvalidShape, "semi-axis b must be a positive number"},
+	} {
+		g, err := DecodeShape([]byte(c.data))
+		if err == nil || !strings.Contains(err.Error(), c.msg) || (c.target != nil && !errors.Is(err, c.target)) {
+			t.Errorf("%s: DecodeShape = %v, want an error %q matching %v", c.name, err, c.msg, c.target)
+		}
+		// A nil interface, not a zero-valued (or nil pointer) shape inside a non-nil one
+		if g != nil {
+			t.Errorf("%s: DecodeShape returned %#v along with its error, want nil", c.name, g)
+		}
+	}
+
+	var ue *UnknownShapeError
+	if _, err := DecodeShape([]byte(`{"type": "hexagon"}`)); !errors.As(err, &ue) || ue.Type != "hexagon" {
+		t.Errorf("DecodeShape(hexagon) = %v, want an *UnknownShapeError", err)
+	}
+	// The position of the failing shape is given
+	var shapes ShapeList
+	err := json.Unmarshal([]byte(`[{"type": "circle", "radius": 1}, {"type": "circle", "radius": -1}]`), &shapes)
+	if !errors.Is(err, ErrInvalidShape) || !strings.Contains(err.Error(), "shape #1") {
+		t.Errorf("Unmarshal(ShapeList) = %v, want shape #1 invalid", err)
+	}
+}
+
+func TestUnmarshalConcreteShape(t *testing.T) {
+	var c circle
+	if err := json.Unmarshal([]byte(`{"type": "circle", "radius": 2.5}`), &c); err != nil || c != (circle{radius: 2.5}) {
+		t.Errorf("Unmarshal(circle) = %v, %v", c, err)
+	}
+	// The "type" tag must match
+	if err := json.Unmarshal([]byte(`{"type": "square", "side": 2}`), &c); err == nil || c != (circle{radius: 2.5}) {
+		t.Errorf("Unmarshal(square into circle) = %v, left %v", err, c)
+	}
+	var r rect2
+	if err := json.Unmarshal([]byte(`{"type": "rectangle", "width": 3, "height": 4}`), &r); err != nil || r != (rect2{width: 3, height: 4}) {
+		t.Errorf("Unmarshal(rect2) = %v, %v", r, err)
+	}
+	if err := json.Unmarshal([]byte(`{"type": "rectangle", "width": -3, "height": 4}`), &r); !errors.Is(err, ErrInvalidShape) {
+		t.Errorf("Unmarshal(invalid rect2) = %v, want %v", err, ErrInvalidShape)
+	}
+}
@@ -0,0 +1,381 @@
+// `measure` of `18-interfaces` chapter only prints numbers. Here we draw shapes: as SVG (an image format which is
+// just text, viewable in any browser), and as ASCII art right in the terminal.
+//
+// Drawing needs more than `area2` & `perim2`, but we don't want to add methods to `geometry` (every existing
+// implementation would stop compiling). Instead, shapes that can be drawn also implement a 2nd, optional,
+// interface: `Drawable`. The renderers check for it with a type assertion, just like `detectCircle` checks for
+// `circle` (a shape that isn't `Drawable` is skipped). The standard library does this a lot (ex: `io.Copy` checks
+// whether its reader also implements `io.WriterTo`).
+//
+// Each shape is drawn in its own coordinates (a circle centred on (0, 0), a rectangle starting at (0, 0), etc.),
+// and the renderer places them side by side.
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Axis-aligned box: the smallest rectangle enclosing a shape
+type box struct {
+	min, max point
+}
+
+func (b box) width() float64 {
+	return b.max.x - b.min.x
+}
+
+func (b box) height() float64 {
+	return b.max.y - b.min.y
+}
+
+// Smallest box enclosing both boxes
+func (b box) union(o box) box {
+	return box{
+		min: point{min(b.min.x, o.min.x), min(b.min.y, o.min.y)},
+		max: point{max(b.max.x, o.max.x), max(b.max.y, o.max.y)},
+	}
+}
+
+func (b box) translate(dx, dy float64) box {
+	return box{point{b.min.x + dx, b.min.y + dy}, point{b.max.x + dx, b.max.y + dy}}
+}
+
+func boundsOf(vertices []point) box {
+	b := box{vertices[0], vertices[0]}
+	for _, v := range vertices[1:] {
+		b = b.union(box{v, v})
+	}
+	return b
+}
+
+type Drawable interface {
+	geometry
+	// Smallest box enclosing the shape, in the shape's own coordinates
+	Bounds() box
+	// Whether `p` (in the shape's own coordinates) is inside the shape (or on its edge)
+	Contains(p point) bool
+	// SVG element drawing the shape in its own coordinates (without any style: the renderer adds it)
+	SVGElement() string
+}
+
+// Shapes made of straight edges
+
+// Ray casting: a horizontal ray starting from `p` crosses the edges an odd number of times iff `p` is inside
+func pointInPolygon(vertices []point, p point) bool {
+	inside := false
+	for i, a := range vertices {
+		b := vertices[(i+1)%len(vertices)]
+		if orientation(a, b, p) == 0 && onSegment(a, b, p) {
+			return true
+		}
+		if (a.y > p.y) != (b.y > p.y) && p.x < a.x+(p.y-a.y)*(b.x-a.x)/(b.y-a.y) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Rounded to 6 decimals, to avoid writing rounding errors like `1.5000000000000002` or `-3.33066907387547e-16`
+func svgNumber(v float64) string {
+	v = math.Round(v*1e6) / 1e6
+	if v == 0 {
+		// Also turns -0 into 0
+		return "0"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func svgPolygon(vertices []point) string {
+	coords := make([]string, len(vertices))
+	for i, v := range vertices {
+		coords[i] = svgNumber(v.x) + "," + svgNumber(v.y)
+	}
+	return fmt.Sprintf(`<polygon points="%s"/>`, strings.Join(coords, " "))
+}
+
+func (r *rect2) vertices() []point {
+	return []point{{0, 0}, {r.width, 0}, {r.width, r.height}, {0, r.height}}
+}
+
+// Fyi, methods of `*rect2` since `area2` already requires a pointer: a `rect2` value isn't a `geometry`,
+// so it can't be a `Drawable` either (see `18-interfaces` chapter)
+func (r *rect2) Bounds() box {
+	return box{point{0, 0}, point{r.width, r.height}}
+}
+
+func (r *rect2) Contains(p point) bool {
+	return p.x >= 0 && p.x <= r.width && p.y >= 0 && p.y <= r.height
+}
+
+func (r *rect2) SVGElement() string {
+	return fmt.Sprintf(`<rect width="%g" height="%g"/>`, r.width, r.height)
+}
+
+func (s square) vertices() []point {
+	return []point{{0, 0}, {s.side, 0}, {s.side, s.side}, {0, s.side}}
+}
+
+func (s square) Bounds() box {
+	return box{point{0, 0}, point{s.side, s.side}}
+}
+
+func (s square) Contains(p point) bool {
+	return p.x >= 0 && p.x <= s.side && p.y >= 0 && p.y <= s.side
+}
+
+func (s square) SVGElement() string {
+	return fmt.Sprintf(`<rect width="%g" height="%g"/>`, s.side, s.side)
+}
+
+// Side `c` lies on the x axis, from (0, 0) to (c, 0). The 3rd vertex is found with the law of cosines.
+func (t triangle) vertices() []point {
+	x := (t.b*t.b + t.c*t.c - t.a*t.a) / (2 * t.c)
+	return []point{{0, 0}, {t.c, 0}, {x, math.Sqrt(max(t.b*t.b-x*x, 0))}}
+}
+
+func (t triangle) Bounds() box {
+	return boundsOf(t.vertices())
+}
+
+func (t triangle) Contains(p point) bool {
+	return pointInPolygon(t.vertices(), p)
+}
+
+func (t triangle) SVGElement() string {
+	return svgPolygon(t.vertices())
+}
+
+// Centred on (0, 0), with a horizontal bottom side
+func (p regularPolygon) vertices() []point {
+	n := float64(p.sides)
+	// Distance from the centre to each vertex
+	radius := p.side / (2 * math.Sin(math.Pi/n))
+	vertices := make([]point, p.sides)
+	for i := range vertices {
+		angle := -math.Pi/2 + math.Pi/n + 2*math.Pi*float64(i)/n
+		vertices[i] = point{radius * math.Cos(angle), radius * math.Sin(angle)}
+	}
+	return vertices
+}
+
+func (p regularPolygon) Bounds() box {
+	return boundsOf(p.vertices())
+}
+
+func (p regularPolygon) Contains(q point) bool {
+	return pointInPolygon(p.vertices(), q)
+}
+
+func (p regularPolygon) SVGElement() string {
+	return svgPolygon(p.vertices())
+}
+
+func (p polygon) Bounds() box {
+	return boundsOf(p.vertices)
+}
+
+func (p polygon) Contains(q point) bool {
+	return pointInPolygon(p.vertices, q)
+}
+
+func (p polygon) SVGElement() string {
+	return svgPolygon(p.vertices)
+}
+
+// Curved shapes, centred on (0, 0)
+
+func (c circle) Bounds() box {
+	return box{point{-c.radius, -c.radius}, point{c.radius, c.radius}}
+}
+
+func (c circle) Contains(p point) bool {
+	return p.x*p.x+p.y*p.y <= c.radius*c.radius
+}
+
+func (c circle) SVGElement() string {
+	return fmt.Sprintf(`<circle r="%g"/>`, c.radius)
+}
+
+func (e ellipse) Bounds() box {
+	return box{point{-e.a, -e.b}, point{e.a, e.b}}
+}
+
+func (e ellipse) Contains(p point) bool {
+	return (p.x*p.x)/(e.a*e.a)+(p.y*p.y)/(e.b*e.b) <= 1
+}
+
+func (e ellipse) SVGElement() string {
+	return fmt.Sprintf(`<ellipse rx="%g" ry="%g"/>`, e.a, e.b)
+}
+
+// Renderers
+
+type SVGStyle struct {
+	// Any SVG/CSS color (`"black"`, `"#ff8800"`, `"none"`, etc.)
+	Stroke      string
+	StrokeWidth float64
+	// Fill colors, used in turn by the shapes (1 color = same fill for all)
+	Fill []string
+	// Space around & between the shapes
+	Padding float64
+}
+
+var DefaultSVGStyle = SVGStyle{Stroke: "black", StrokeWidth: 0.1, Fill: []string{"lightblue", "lightgreen", "khaki", "salmon"}, Padding: 1}
+
+// A drawable shape and where the renderer put it
+type placedShape struct {
+	Drawable
+	index  int
+	offset point
+}
+
+// Places the drawable shapes side by side (left to right, aligned on the bottom), `gap` apart.
+// Also returns the box enclosing all of them.
+func layoutShapes(shapes []geometry, gap float64) ([]placedShape, box) {
+	var placed []placedShape
+	var all box
+	x := 0.0
+	for i, g := range shapes {
+		d, ok := g.(Drawable)
+		if !ok {
+			continue
+		}
+		b := d.Bounds()
+		offset := point{x - b.min.x, 0 - b.min.y}
+		placed = append(placed, placedShape{Drawable: d, index: i, offset: offset})
+		if len(placed) == 1 {
+			all = b.translate(offset.x, offset.y)
+		} else {
+			all = all.union(b.translate(offset.x, offset.y))
+		}
+		x += b.width() + gap
+	}
+	return placed, all
+}
+
+// Writes an SVG image of all the `Drawable` shapes (the others are listed in a comment)
+func RenderSVG(w io.Writer, shapes []geometry, style SVGStyle) error {
+	placed, all := layoutShapes(shapes, style.Padding)
+	if len(placed) == 0 {
+		return fmt.Errorf("no drawable shape among %d shapes", len(shapes))
+	}
+
+	// The viewBox is the part of the (infinite) SVG plane shown in the image: the shapes plus some padding.
+	// SVG's y axis points down, whereas ours points up: the whole drawing is flipped (`scale(1,-1)`), so its
+	// y coordinates are negated in the viewBox as well.
+	p := style.Padding
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="%g %g %g %g">`+"\n",
+		all.min.x-p, -all.max.y-p, all.width()+2*p, all.height()+2*p)
+	// The colors come from the caller: escaped, so that a `"` or `<` in them can't break out of the attribute
+	fmt.Fprintf(&sb, `  <g transform="scale(1,-1)" stroke="%s" stroke-width="%g">`+"\n", html.EscapeString(style.Stroke), style.StrokeWidth)
+	for i, s := range placed {
+		fill := "none"
+		if len(style.Fill) > 0 {
+			fill = html.EscapeString(style.Fill[i%len(style.Fill)])
+		}
+		fmt.Fprintf(&sb, `    <g transform="translate(%s,%s)" fill="%s">%s</g>`+"\n", svgNumber(s.offset.x), svgNumber(s.offset.y), fill, s.SVGElement())
+	}
+	sb.WriteString("  </g>\n")
+
+	j := 0
+	for i, g := range shapes {
+		if j < len(placed) && placed[j].index == i {
+			j++
+			continue
+		}
+		fmt.Fprintf(&sb, "  <!-- %T is not drawable -->\n", g)
+	}
+	sb.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Characters filling the shapes, used in turn
+const asciiFills = "#*o+x%@="
+
+// Writes the `Drawable` shapes as ASCII art, `cols` characters wide.
+//
+// Each character is a small cell of the plane, filled if its centre is inside a shape. A terminal character
+// is about twice as tall as wide, so a cell covers twice more height than width (otherwise circles look tall).
+func RenderASCII(w io.Writer, shapes []geometry, cols int) error {
+	if cols < 1 {
+		return fmt.Errorf("ascii art width must be at least 1 column, got %d", cols)
+	}
+	placed, all := layoutShapes(shapes, 1)
+	if len(placed) == 0 {
+		return fmt.Errorf("no drawable shape among %d shapes", len(shapes))
+	}
+
+	cell := all.width() / float64(cols)
+	rows := max(int(math.Ceil(all.height()/(2*cell))), 1)
+
+	var sb strings.Builder
+	// From the top row (highest y) to the bottom one
+	for r := rows - 1; r >= 0; r-- {
+		line := make([]byte, cols)
+		for c := range line {
+			line[c] = ' '
+			p := point{all.min.x + (float64(c)+0.5)*cell, all.min.y + (float64(r)+0.5)*2*cell}
+			for i, s := range placed {
+				if s.Contains(point{p.x - s.offset.x, p.y - s.offset.y}) {
+					line[c] = asciiFills[i%len(asciiFills)]
+					break
+				}
+			}
+		}
+		sb.WriteString(strings.TrimRight(string(line), " "))
+		sb.WriteByte('\n')
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Not drawable: only implements `geometry`
+type blob struct{}
+
+func (blob) area2() float64  { return 1 }
+func (blob) perim2() float64 { return 4 }
+
+func shape_rendering_main() {
+	r, _ := newRect2(3, 4)
+	c, _ := newCircle(2.5)
+	t, _ := newTriangle(3, 4, 5)
+	p, _ := newRegularPolygon(6, 1.5)
+	e, _ := newEllipse(3, 1.5)
+	shapes := []geometry{r, c, t, blob{}, p, e}
+
+	// Like `detectCircle`: a type assertion tells whether a shape can be drawn
+	for _, g := range shapes {
+		if d, ok := g.(Drawable); ok {
+			fmt.Printf("%T is drawable, bounds %v\n", g, d.Bounds())
+		} else {
+			fmt.Printf("%T is not drawable\n", g)
+		}
+	}
+
+	if err := RenderASCII(os.Stdout, shapes, 72); err != nil {
+		fmt.Println(err)
+	}
+
+	style := DefaultSVGStyle
+	style.Stroke = "navy"
+	if err := RenderSVG(os.Stdout, shapes, style); err != nil {
+		fmt.Println(err)
+	}
+
+	// Outline only
+	style.Fill = nil
+	if err := RenderSVG(os.Stdout, shapes[:2], style); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println(RenderSVG(os.Stdout, []geometry{blob{}}, style))
+}
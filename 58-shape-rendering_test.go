@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRenderASCIIColumns(t *testing.T) {
+	c, _ := newCircle(2)
+	for _, cols := range []int{-1, 0} {
+		if err := RenderASCII(io.Discard, []geometry{c}, cols); err == nil {
+			t.Errorf("RenderASCII(%d columns) = nil, want an error", cols)
+		}
+	}
+	var sb strings.Builder
+	if err := RenderASCII(&sb, []geometry{c}, 1); err != nil || sb.String() == "" {
+		t.Errorf("RenderASCII(1 column) = %v, output %q", err, sb.String())
+	}
+}
+
+// Whatever the style's colors, the SVG stays well-formed XML
+func TestRenderSVGEscapesStyle(t *testing.T) {
+	c, _ := newCircle(2)
+	r, _ := newRect2(1, 2)
+	style := DefaultSVGStyle
+	style.Stroke = `red" onload="alert(1)`
+	style.Fill = []string{"<blue>", "a&b"}
+
+	var sb strings.Builder
+	if err := RenderSVG(&sb, []geometry{c, r}, style); err != nil {
+		t.Fatal(err)
+	}
+	svg := sb.String()
+
+	dec := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid XML: %v\n%s", err, svg)
+		}
+		if el, ok := tok.(xml.StartElement); ok {
+			for _, attr := range el.Attr {
+				if attr.Name.Local == "onload" {
+					t.Errorf("stroke color injected an attribute:\n%s", svg)
+				}
+			}
+		}
+	}
+	if !strings.Contains(svg, `fill="&lt;blue&gt;"`) {
+		t.Errorf("fill not escaped:\n%s", svg)
+	}
+}
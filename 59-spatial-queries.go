@@ -0,0 +1,334 @@
+// The shapes of `56-shape-catalogue` chapter have a size but no position: "is this point inside?" or "do these 2
+// shapes overlap?" can't be answered. Here we place shapes in the plane (`Positioned`: a `Drawable` shape plus
+// the point where its own coordinates' origin lies), then:
+//   - test whether a point is inside a placed shape, and whether 2 placed shapes overlap
+//   - index thousands of placed shapes, to find the ones containing a point without testing all of them
+//
+// The index is a uniform grid: the plane is cut into square cells, and each shape is listed in every cell its
+// bounding box touches. A query only tests the shapes listed in the point's cell. It works well when the shapes
+// have similar sizes and are spread out (an R-tree adapts to any distribution, but is way more complex).
+// See the benchmarks against testing every shape in `59-spatial-queries_test.go` (`go test -bench ShapesAt`).
+package main
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+// A shape placed in the plane: its own coordinates are moved by `Origin`
+type Positioned struct {
+	Shape  Drawable
+	Origin point
+}
+
+func Place(shape Drawable, x, y float64) Positioned {
+	return Positioned{Shape: shape, Origin: point{x, y}}
+}
+
+// `Positioned` is itself a `Drawable` (in the plane's coordinates), so it can go wherever a shape can
+
+func (p Positioned) area2() float64 {
+	return p.Shape.area2()
+}
+
+func (p Positioned) perim2() float64 {
+	return p.Shape.perim2()
+}
+
+func (p Positioned) Bounds() box {
+	return p.Shape.Bounds().translate(p.Origin.x, p.Origin.y)
+}
+
+func (p Positioned) Contains(q point) bool {
+	return p.Shape.Contains(point{q.x - p.Origin.x, q.y - p.Origin.y})
+}
+
+func (p Positioned) SVGElement() string {
+	return fmt.Sprintf(`<g transform="translate(%s,%s)">%s</g>`, svgNumber(p.Origin.x), svgNumber(p.Origin.y), p.Shape.SVGElement())
+}
+
+// Whether 2 boxes share at least 1 point
+func (b box) overlaps(o box) bool {
+	return b.min.x <= o.max.x && o.min.x <= b.max.x && b.min.y <= o.max.y && o.min.y <= b.max.y
+}
+
+// Number of vertices approximating an ellipse in intersection tests
+const ellipseSegments = 64
+
+func (e ellipse) approxVertices(n int) []point {
+	vertices := make([]point, n)
+	for i := range vertices {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		vertices[i] = point{e.a * math.Cos(angle), e.b * math.Sin(angle)}
+	}
+	return vertices
+}
+
+// Outline of a placed shape, in the plane's coordinates: either a circle (`vertices` is nil) or a polygon.
+// Fyi, `polygon` can't have a `vertices()` method like the others, as it already has a `vertices` field.
+//
+// `Place` accepts any `Drawable`, including ones defined elsewhere that we know nothing about: their outline is
+// their bounding box (an approximation, exact only for axis-aligned rectangles).
+func (p Positioned) outline() (center point, radius float64, vertices []point) {
+	switch s := p.Shape.(type) {
+	case circle:
+		radius = s.radius
+	case polygon:
+		vertices = s.vertices
+	case ellipse:
+		// An approximation (exact ellipse intersections need to solve 4th degree equations)
+		vertices = s.approxVertices(ellipseSegments)
+	case Positioned:
+		// Placed twice: both origins add up
+		center, radius, vertices = s.outline()
+	case interface{ vertices() []point }:
+		vertices = s.vertices()
+	default:
+		b := p.Shape.Bounds()
+		vertices = []point{b.min, {b.max.x, b.min.y}, b.max, {b.min.x, b.max.y}}
+	}
+
+	center = point{center.x + p.Origin.x, center.y + p.Origin.y}
+	if vertices == nil {
+		return center, radius, nil
+	}
+	moved := make([]point, len(vertices))
+	for i, v := range vertices {
+		moved[i] = point{v.x + p.Origin.x, v.y + p.Origin.y}
+	}
+	return center, 0, moved
+}
+
+// Axis-aligned rectangles are equal to their bounding box
+func isAxisAligned(d Drawable) bool {
+	switch d.(type) {
+	case *rect2, square:
+		return true
+	}
+	return false
+}
+
+// Distance from `p` to the closest point of segment [a, b]
+func distToSegment(p, a, b point) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	lengthSq := dx*dx + dy*dy
+	if lengthSq == 0 {
+		return p.dist(a)
+	}
+	// Position of `p`'s projection on the segment: 0 at `a`, 1 at `b` (clamped to stay on the segment)
+	t := max(0, min(1, ((p.x-a.x)*dx+(p.y-a.y)*dy)/lengthSq))
+	return p.dist(point{a.x + t*dx, a.y + t*dy})
+}
+
+func circleIntersectsPolygon(center point, radius float64, vertices []point) bool {
+	// The centre is inside (this covers a circle fully inside the polygon)
+	if pointInPolygon(vertices, center) {
+		return true
+	}
+	// Or an edge passes close enough to the centre (this covers the polygon fully inside the circle too)
+	for i, a := range vertices {
+		if distToSegment(center, a, vertices[(i+1)%len(vertices)]) <= radius {
+			return true
+		}
+	}
+	return false
+}
+
+func polygonsIntersect(a, b []point) bool {
+	// Edges crossing each other
+	for i := range a {
+		for j := range b {
+			if segmentsIntersect(a[i], a[(i+1)%len(a)], b[j], b[(j+1)%len(b)]) {
+				return true
+			}
+		}
+	}
+	// No edge crosses: either one polygon is fully inside the other, or they are apart
+	return pointInPolygon(b, a[0]) || pointInPolygon(a, b[0])
+}
+
+// Whether 2 placed shapes share at least 1 point (touching counts)
+func Intersects(a, b Positioned) bool {
+	// Cheap test first: most pairs of shapes are far from each other
+	if !a.Bounds().overlaps(b.Bounds()) {
+		return false
+	}
+	if isAxisAligned(a.Shape) && isAxisAligned(b.Shape) {
+		return true
+	}
+
+	ca, ra, va := a.outline()
+	cb, rb, vb := b.outline()
+	switch {
+	case va == nil && vb == nil:
+		return ca.dist(cb) <= ra+rb
+	case va == nil:
+		return circleIntersectsPolygon(ca, ra, vb)
+	case vb == nil:
+		return circleIntersectsPolygon(cb, rb, va)
+	default:
+		return polygonsIntersect(va, vb)
+	}
+}
+
+// Uniform grid index
+
+type cellKey struct {
+	x, y int
+}
+
+type ShapeGrid struct {
+	cellSize float64
+	shapes   []Positioned
+	// Indexes (in `shapes`) of the shapes whose bounding box touches each cell. Only non-empty cells are stored,
+	// so the plane doesn't need to be bounded.
+	cells map[cellKey][]int
+}
+
+// `cellSize` should be around the size of a typical shape: too small and each shape is listed in lots of cells,
+// too big and each cell lists lots of shapes.
+// It must be positive & finite: coordinates are divided by it, and the results converted to cell numbers.
+func NewShapeGrid(cellSize float64) (*ShapeGrid, error) {
+	if !(cellSize > 0) || math.IsInf(cellSize, 1) {
+		return nil, fmt.Errorf("invalid grid cell size %g: must be positive and finite", cellSize)
+	}
+	return &ShapeGrid{cellSize: cellSize, cells: map[cellKey][]int{}}, nil
+}
+
+func (g *ShapeGrid) cellOf(p point) cellKey {
+	return cellKey{int(math.Floor(p.x / g.cellSize)), int(math.Floor(p.y / g.cellSize))}
+}
+
+// Cells touched by box `b`
+func (g *ShapeGrid) cellsOf(b box) iter.Seq[cellKey] {
+	return func(yield func(cellKey) bool) {
+		lo, hi := g.cellOf(b.min), g.cellOf(b.max)
+		for x := lo.x; x <= hi.x; x++ {
+			for y := lo.y; y <= hi.y; y++ {
+				if !yield(cellKey{x, y}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Adds a shape, and returns its index (used by the queries to designate it)
+func (g *ShapeGrid) Add(s Positioned) int {
+	id := len(g.shapes)
+	g.shapes = append(g.shapes, s)
+	for key := range g.cellsOf(s.Bounds()) {
+		g.cells[key] = append(g.cells[key], id)
+	}
+	return id
+}
+
+func (g *ShapeGrid) Shape(id int) Positioned {
+	return g.shapes[id]
+}
+
+// Indexes of the shapes containing `p`, in ascending order
+func (g *ShapeGrid) At(p point) []int {
+	var ids []int
+	// Each shape is listed only once per cell, so no duplicates here
+	for _, id := range g.cells[g.cellOf(p)] {
+		if g.shapes[id].Contains(p) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Indexes of the shapes intersecting `s`, in ascending order
+func (g *ShapeGrid) Overlapping(s Positioned) []int {
+	// A shape spanning several cells is listed in each of them: `seen` avoids testing (and returning) it twice
+	seen := map[int]bool{}
+	var ids []int
+	for key := range g.cellsOf(s.Bounds()) {
+		for _, id := range g.cells[key] {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if Intersects(g.shapes[id], s) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Same as `ShapeGrid.At`, testing every shape
+func shapesAt(shapes []Positioned, p point) []int {
+	var ids []int
+	for id, s := range shapes {
+		if s.Contains(p) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// `n` random shapes (of size 1 to 20) spread over a 1000x1000 area
+func randomPlacedShapes(rng *rand.Rand, n int) []Positioned {
+	shapes := make([]Positioned, n)
+	for i := range shapes {
+		size := 1 + rng.Float64()*19
+		var d Drawable
+		switch rng.IntN(3) {
+		case 0:
+			d, _ = newRect2(size, 1+rng.Float64()*19)
+		case 1:
+			d, _ = newCircle(size / 2)
+		default:
+			d, _ = newRegularPolygon(3+rng.IntN(6), size/2)
+		}
+		shapes[i] = Place(d, rng.Float64()*1000, rng.Float64()*1000)
+	}
+	return shapes
+}
+
+func spatial_queries_main() {
+	r, _ := newRect2(4, 2)
+	c, _ := newCircle(1)
+	t, _ := newTriangle(3, 4, 5)
+	rect := Place(r, 0, 0)
+	near := Place(c, 5, 1)
+	far := Place(c, 10, 10)
+	tri := Place(t, 3.5, 1.5)
+
+	fmt.Println("rect bounds:", rect.Bounds(), "- circle bounds:", near.Bounds())
+	fmt.Println("rect contains (1, 1):", rect.Contains(point{1, 1}), "- (5, 1):", rect.Contains(point{5, 1}))
+	fmt.Println("rect/near circle:", Intersects(rect, near))
+	fmt.Println("rect/far circle:", Intersects(rect, far))
+	fmt.Println("rect/triangle:", Intersects(rect, tri))
+	fmt.Println("triangle/near circle:", Intersects(tri, near))
+	fmt.Println("near/far circles:", Intersects(near, far))
+	// Bounding boxes overlap, but not the shapes: the circle sits in the corner outside of the triangle
+	fmt.Println("triangle/corner circle:", Intersects(Place(t, 0, 0), Place(c, 0.3, 2)))
+
+	// 5000 shapes: the grid finds the same shapes as testing all of them, much faster
+	rng := rand.New(rand.NewPCG(42, 42))
+	shapes := randomPlacedShapes(rng, 5000)
+	grid, _ := NewShapeGrid(20)
+	for _, s := range shapes {
+		grid.Add(s)
+	}
+	for range 1000 {
+		p := point{rng.Float64() * 1000, rng.Float64() * 1000}
+		if !slices.Equal(grid.At(p), shapesAt(shapes, p)) {
+			fmt.Println("grid and brute force disagree at", p)
+		}
+	}
+	p := point{500, 500}
+	fmt.Println("shapes containing", p, grid.At(p))
+	fmt.Println("shapes overlapping shape 0:", grid.Overlapping(shapes[0]))
+
+	_, err := NewShapeGrid(0)
+	fmt.Println(err)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// A `Drawable` unknown to `Intersects`: a circle under another name
+type customShape struct {
+	circle
+}
+
+func TestIntersectsUnknownDrawable(t *testing.T) {
+	c, _ := newCircle(1)
+	r, _ := newRect2(1, 1)
+	for _, tc := range []struct {
+		name string
+		a, b Positioned
+		want bool
+	}{
+		{"overlapping", Place(customShape{c}, 0, 0), Place(customShape{c}, 0.5, 0), true},
+		{"apart", Place(customShape{c}, 0, 0), Place(customShape{c}, 5, 0), false},
+		{"with a rectangle", Place(customShape{c}, 0, 0), Place(r, 0.5, 0.5), true},
+		{"placed twice", Place(Place(customShape{c}, 1, 1), 1, 1), Place(c, 2, 2), true},
+	} {
+		if got := Intersects(tc.a, tc.b); got != tc.want {
+			t.Errorf("%s: Intersects = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestNewShapeGridInvalidCellSize(t *testing.T) {
+	for _, size := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		if _, err := NewShapeGrid(size); err == nil {
+			t.Errorf("NewShapeGrid(%g) = nil error, want an error", size)
+		}
+	}
+}
+
+// The grid finds the same shapes as testing all of them
+func TestShapeGridMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewPCG(42, 42))
+	shapes := randomPlacedShapes(rng, 2000)
+	grid, err := NewShapeGrid(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range shapes {
+		grid.Add(s)
+	}
+
+	for range 1000 {
+		p := point{rng.Float64() * 1000, rng.Float64() * 1000}
+		if got, want := grid.At(p), shapesAt(shapes, p); !slices.Equal(got, want) {
+			t.Errorf("At(%v) = %v, brute force = %v", p, got, want)
+		}
+	}
+	for _, s := range shapes[:100] {
+		var want []int
+		for id, o := range shapes {
+			if Intersects(o, s) {
+				want = append(want, id)
+			}
+		}
+		if got := grid.Overlapping(s); !slices.Equal(got, want) {
+			t.Errorf("Overlapping(%v) = %v, brute force = %v", s, got, want)
+		}
+	}
+}
+
+func BenchmarkShapesAt(b *testing.B) {
+	shapes := randomPlacedShapes(rand.New(rand.NewPCG(42, 42)), 5000)
+	grid, _ := NewShapeGrid(20)
+	for _, s := range shapes {
+		grid.Add(s)
+	}
+	for _, bench := range []struct {
+		name string
+		at   func(p point) []int
+	}{
+		{"brute-force", func(p point) []int { return shapesAt(shapes, p) }},
+		{"grid", grid.At},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			rng := rand.New(rand.NewPCG(1, 2))
+			for i := 0; i < b.N; i++ {
+				bench.at(point{rng.Float64() * 1000, rng.Float64() * 1000})
+			}
+		})
+	}
+}
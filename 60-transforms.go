@@ -0,0 +1,424 @@
+// So far shapes are built once and never change. Here we give them operations: scaling, moving & rotating
+// (these are "affine transforms": they keep straight lines straight).
+//
+// Each operation comes in 2 flavours, to compare pointer & value receivers (see `17-methods` & `18-interfaces` chapters):
+//   - `Scale(k)`, `Translate(dx, dy)`, `Rotate(theta)`: pointer receivers, they MODIFY the shape
+//   - `Scaled(k)`, `Translated(dx, dy)`, `Rotated(theta)`: value receivers, they RETURN a modified copy
+//
+// The pitfalls that come with it are shown in `transforms_main`, and tested in `60-transforms_test.go`.
+//
+// The catalogue's shapes have no position (a circle is always centred on (0, 0)) and no orientation (a `rect2`'s sides
+// are always horizontal & vertical): apart from `polygon` (made of its vertices), they can only be scaled. To move &
+// rotate any of them, wrap it in a `Transformed`, which holds the position & the angle (a `Transformer` for all shapes).
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Like `io.ReadWriter` is made of `io.Reader` & `io.Writer`, bigger interfaces are made of smaller ones
+type Scaler interface {
+	Scale(k float64)
+}
+
+type Transformer interface {
+	Scaler
+	Translate(dx, dy float64)
+	// Counterclockwise, in radians, around (0, 0)
+	Rotate(theta float64)
+}
+
+// Scaling by 0 or less would give an invalid shape (see `56-shape-catalogue` chapter). As `Scale` can't return an
+// error (its flavour returning a copy already returns the shape), it's a programming error, like an index out of range.
+func checkScale(k float64) {
+	if !(k > 0) || math.IsInf(k, 1) {
+		panic(&ShapeError{Shape: "scaled shape", Reason: fmt.Sprintf("scale factor must be a positive number, got %v", k)})
+	}
+}
+
+func (p point) scaled(k float64) point {
+	return point{p.x * k, p.y * k}
+}
+
+func (p point) translated(dx, dy float64) point {
+	return point{p.x + dx, p.y + dy}
+}
+
+func (p point) rotated(theta float64) point {
+	sin, cos := math.Sincos(theta)
+	return point{p.x*cos - p.y*sin, p.x*sin + p.y*cos}
+}
+
+// Shapes without position: scaling only
+
+func (r *rect2) Scale(k float64) {
+	checkScale(k)
+	r.width *= k
+	r.height *= k
+}
+
+// Fyi, `r` is already a copy here (value receiver): modifying it and returning it doesn't touch the caller's `rect2`
+func (r rect2) Scaled(k float64) rect2 {
+	r.Scale(k)
+	return r
+}
+
+func (c *circle) Scale(k float64) {
+	checkScale(k)
+	c.radius *= k
+}
+
+func (c circle) Scaled(k float64) circle {
+	c.Scale(k)
+	return c
+}
+
+func (s *square) Scale(k float64) {
+	checkScale(k)
+	s.side *= k
+}
+
+func (s square) Scaled(k float64) square {
+	s.Scale(k)
+	return s
+}
+
+func (t *triangle) Scale(k float64) {
+	checkScale(k)
+	t.a, t.b, t.c = t.a*k, t.b*k, t.c*k
+}
+
+func (t triangle) Scaled(k float64) triangle {
+	t.Scale(k)
+	return t
+}
+
+func (p *regularPolygon) Scale(k float64) {
+	checkScale(k)
+	p.side *= k
+}
+
+func (p regularPolygon) Scaled(k float64) regularPolygon {
+	p.Scale(k)
+	return p
+}
+
+func (e *ellipse) Scale(k float64) {
+	checkScale(k)
+	e.a, e.b = e.a*k, e.b*k
+}
+
+func (e ellipse) Scaled(k float64) ellipse {
+	e.Scale(k)
+	return e
+}
+
+// Polygon: all transforms
+
+func (p *polygon) transform(f func(point) point) {
+	for i, v := range p.vertices {
+		p.vertices[i] = f(v)
+	}
+}
+
+// Copies the vertices before modifying them. A value receiver copies the struct, but a struct copy shares its
+// slices' backing arrays with the original (see `8-slices` chapter): `p.transform` alone would modify the caller's polygon!
+func (p polygon) transformed(f func(point) point) polygon {
+	p.vertices = append([]point(nil), p.vertices...)
+	p.transform(f)
+	return p
+}
+
+// Around (0, 0): vertices get further from it
+func (p *polygon) Scale(k float64) {
+	checkScale(k)
+	p.transform(func(v point) point { return v.scaled(k) })
+}
+
+func (p *polygon) Translate(dx, dy float64) {
+	p.transform(func(v point) point { return v.translated(dx, dy) })
+}
+
+func (p *polygon) Rotate(theta float64) {
+	p.transform(func(v point) point { return v.rotated(theta) })
+}
+
+func (p polygon) Scaled(k float64) polygon {
+	checkScale(k)
+	return p.transformed(func(v point) point { return v.scaled(k) })
+}
+
+func (p polygon) Translated(dx, dy float64) polygon {
+	return p.transformed(func(v point) point { return v.translated(dx, dy) })
+}
+
+func (p polygon) Rotated(theta float64) polygon {
+	return p.transformed(func(v point) point { return v.rotated(theta) })
+}
+
+// Positioned shapes (see `59-spatial-queries` chapter): all transforms
+
+// A `Drawable` defined elsewhere, that we know nothing about (see `blob` in `58-shape-rendering` chapter), scaled
+// by `k` around (0, 0). Fyi, the SVG scales its stroke as well.
+type scaledDrawable struct {
+	Drawable
+	k float64
+}
+
+func (s scaledDrawable) area2() float64 {
+	return s.Drawable.area2() * s.k * s.k
+}
+
+func (s scaledDrawable) perim2() float64 {
+	return s.Drawable.perim2() * s.k
+}
+
+func (s scaledDrawable) Bounds() box {
+	b := s.Drawable.Bounds()
+	return box{b.min.scaled(s.k), b.max.scaled(s.k)}
+}
+
+func (s scaledDrawable) Contains(p point) bool {
+	return s.Drawable.Contains(p.scaled(1 / s.k))
+}
+
+func (s scaledDrawable) SVGElement() string {
+	return fmt.Sprintf(`<g transform="scale(%s)">%s</g>`, svgNumber(s.k), s.Drawable.SVGElement())
+}
+
+// Same, rotated by `theta` around (0, 0)
+type rotatedDrawable struct {
+	Drawable
+	theta float64
+}
+
+// The box enclosing the rotated corners of the shape's box: it encloses the shape, but may be bigger than needed
+func (r rotatedDrawable) Bounds() box {
+	b := r.Drawable.Bounds()
+	corners := []point{b.min, {b.max.x, b.min.y}, b.max, {b.min.x, b.max.y}}
+	for i, c := range corners {
+		corners[i] = c.rotated(r.theta)
+	}
+	return boundsOf(corners)
+}
+
+func (r rotatedDrawable) Contains(p point) bool {
+	return r.Drawable.Contains(p.rotated(-r.theta))
+}
+
+func (r rotatedDrawable) SVGElement() string {
+	return fmt.Sprintf(`<g transform="rotate(%s)">%s</g>`, svgNumber(r.theta*180/math.Pi), r.Drawable.SVGElement())
+}
+
+// The shape scaled by `k` (around its own origin)
+func scaleDrawable(d Drawable, k float64) Drawable {
+	checkScale(k)
+	switch s := d.(type) {
+	case *rect2:
+		scaled := s.Scaled(k)
+		return &scaled
+	case circle:
+		return s.Scaled(k)
+	case square:
+		return s.Scaled(k)
+	case triangle:
+		return s.Scaled(k)
+	case regularPolygon:
+		return s.Scaled(k)
+	case ellipse:
+		return s.Scaled(k)
+	case polygon:
+		return s.Scaled(k)
+	case Positioned:
+		return s.Scaled(k)
+	case Transformed:
+		return s.Scaled(k)
+	case scaledDrawable:
+		// Scaled again: the factors multiply
+		return scaledDrawable{s.Drawable, s.k * k}
+	}
+	return scaledDrawable{d, k}
+}
+
+// The shape rotated by `theta` (around its own origin). Shapes that can't rotate themselves become `polygon`s
+// (or get wrapped, for shapes we know nothing about).
+func rotateDrawable(d Drawable, theta float64) Drawable {
+	switch s := d.(type) {
+	case circle:
+		// Centred on (0, 0): unchanged
+		return s
+	case polygon:
+		return s.Rotated(theta)
+	case ellipse:
+		// An approximation, as we have no type for a rotated ellipse
+		return polygon{vertices: s.approxVertices(ellipseSegments)}.Rotated(theta)
+	case Positioned:
+		return s.Rotated(theta)
+	case Transformed:
+		return s.Rotated(theta)
+	case interface{ vertices() []point }:
+		// `vertices()` builds a new slice each time: no need to copy it
+		p := polygon{vertices: s.vertices()}
+		p.Rotate(theta)
+		return p
+	case rotatedDrawable:
+		return rotatedDrawable{s.Drawable, s.theta + theta}
+	}
+	return rotatedDrawable{d, theta}
+}
+
+// Around (0, 0): like the polygon's vertices, the origin gets further from it
+func (p *Positioned) Scale(k float64) {
+	checkScale(k)
+	p.Shape = scaleDrawable(p.Shape, k)
+	p.Origin = p.Origin.scaled(k)
+}
+
+func (p *Positioned) Translate(dx, dy float64) {
+	p.Origin = p.Origin.translated(dx, dy)
+}
+
+// Around (0, 0): both the origin and the shape turn
+func (p *Positioned) Rotate(theta float64) {
+	p.Shape = rotateDrawable(p.Shape, theta)
+	p.Origin = p.Origin.rotated(theta)
+}
+
+// `Positioned` holds no slice: the shallow copy made by the value receiver is enough (`Shape` is replaced, not modified)
+func (p Positioned) Scaled(k float64) Positioned {
+	p.Scale(k)
+	return p
+}
+
+func (p Positioned) Translated(dx, dy float64) Positioned {
+	p.Translate(dx, dy)
+	return p
+}
+
+func (p Positioned) Rotated(theta float64) Positioned {
+	p.Rotate(theta)
+	return p
+}
+
+// Any shape, moved & rotated: its own coordinates are rotated by `Angle` (counterclockwise, in radians, around its
+// own origin), then moved by `Origin`. Unlike `Positioned` (which only moves a shape), it keeps the shape as is
+// (a rotated `rect2` stays a `rect2`, instead of becoming a `polygon`).
+type Transformed struct {
+	Shape  Drawable
+	Origin point
+	Angle  float64
+}
+
+func Transform(shape Drawable) Transformed {
+	return Transformed{Shape: shape}
+}
+
+// Around (0, 0), like the other transforms: the origin gets further from it
+func (t *Transformed) Scale(k float64) {
+	checkScale(k)
+	t.Shape = scaleDrawable(t.Shape, k)
+	t.Origin = t.Origin.scaled(k)
+}
+
+func (t *Transformed) Translate(dx, dy float64) {
+	t.Origin = t.Origin.translated(dx, dy)
+}
+
+// Around (0, 0): the origin turns, and the shape turns by the same angle
+func (t *Transformed) Rotate(theta float64) {
+	t.Origin = t.Origin.rotated(theta)
+	t.Angle += theta
+}
+
+// Holds no slice: the shallow copy made by the value receiver is enough (`Shape` is replaced, not modified)
+func (t Transformed) Scaled(k float64) Transformed {
+	t.Scale(k)
+	return t
+}
+
+func (t Transformed) Translated(dx, dy float64) Transformed {
+	t.Translate(dx, dy)
+	return t
+}
+
+func (t Transformed) Rotated(theta float64) Transformed {
+	t.Rotate(theta)
+	return t
+}
+
+// The same shape as a `Positioned`, to test its intersections (see `59-spatial-queries` chapter).
+// Fyi, `Intersects` tests any other `Drawable` by its bounding box only.
+func (t Transformed) Placed() Positioned {
+	return Positioned{Shape: rotateDrawable(t.Shape, t.Angle), Origin: t.Origin}
+}
+
+// Moving & rotating keeps the area & the perimeter
+func (t Transformed) area2() float64 {
+	return t.Shape.area2()
+}
+
+func (t Transformed) perim2() float64 {
+	return t.Shape.perim2()
+}
+
+func (t Transformed) Bounds() box {
+	return t.Placed().Bounds()
+}
+
+// `p` in the shape's own coordinates: the transforms undone, in reverse order
+func (t Transformed) Contains(p point) bool {
+	return t.Shape.Contains(p.translated(-t.Origin.x, -t.Origin.y).rotated(-t.Angle))
+}
+
+// Fyi, SVG applies the transforms of a list from right to left
+func (t Transformed) SVGElement() string {
+	return fmt.Sprintf(`<g transform="translate(%s,%s) rotate(%s)">%s</g>`,
+		svgNumber(t.Origin.x), svgNumber(t.Origin.y), svgNumber(t.Angle*180/math.Pi), t.Shape.SVGElement())
+}
+
+func transforms_main() {
+	// A value receiver works on a copy: `Scaled` leaves `r` as is, whereas `Scale` modifies it.
+	// `r` is a variable (addressable): Go calls `(&r).Scale(2)` for us.
+	r := rect2{width: 3, height: 4}
+	big := r.Scaled(2)
+	fmt.Println("scaled copy:", big.width, "x", big.height, "- original:", r.width, "x", r.height)
+	r.Scale(2)
+	fmt.Println("scaled original:", r.width, "x", r.height)
+
+	// Same as `measure(&r)` in `18-interfaces` chapter, only `*rect2` implements `geometry` and `Scaler`
+	// (`var s Scaler = r` doesn't compile). The interface holds a pointer to `r`: scaling through it scales `r`.
+	var s Scaler = &r
+	s.Scale(0.5)
+	fmt.Println("scaled through a Scaler:", r.width, "x", r.height)
+
+	// Moving & rotating needs a `Transformed`. `t` is a variable: `&t` is a `Transformer`.
+	t := Transform(&r)
+	var tr Transformer = &t
+	tr.Translate(1, 1)
+	tr.Rotate(math.Pi / 2)
+	// Fyi, `%.3v` rounds away the rounding errors of `Sincos` (`-0.9999999999999999`)
+	fmt.Printf("rectangle at %.3v turned by %.3v rad, bounds %.3v\n", t.Origin, t.Angle, t.Bounds())
+
+	c, _ := newCircle(1)
+	moved := Transform(c).Translated(3, 0).Rotated(math.Pi).Scaled(2)
+	fmt.Printf("circle at (%.2f, %.2f) of area %.3v\n", moved.Origin.x, moved.Origin.y, moved.area2())
+
+	// A struct copy shares its slices with the original: `Translated` copies the vertices before moving them
+	// (a copy of the struct alone would move the original's vertices too)
+	p, _ := newPolygon(point{0, 0}, point{2, 0}, point{0, 2})
+	fmt.Println("moved polygon:", p.Translated(5, 5).vertices, "- original:", p.vertices)
+
+	// Rotating a placed square (rather than a transformed one) gives a polygon
+	sq, _ := newSquare(2)
+	turned := Place(sq, 1, 0).Rotated(math.Pi / 4)
+	fmt.Printf("%T at %.3v, area %.3v\n", turned.Shape, turned.Origin, turned.area2())
+
+	// Scaling by a negative factor is a programming error
+	func() {
+		defer func() {
+			fmt.Println("recovered:", recover())
+		}()
+		r.Scale(-1)
+	}()
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// Pitfall 1: a value receiver works on a copy. `Scaled` leaves `r` as is, `Scale` modifies it.
+func TestScaledReturnsCopy(t *testing.T) {
+	r := rect2{width: 3, height: 4}
+	big := r.Scaled(2)
+	if big.width != 6 || big.height != 8 {
+		t.Errorf("Scaled(2) = %vx%v, want 6x8", big.width, big.height)
+	}
+	if r.width != 3 || r.height != 4 {
+		t.Errorf("original after Scaled(2) = %vx%v, want 3x4", r.width, r.height)
+	}
+	// `r` is a variable (addressable): Go calls `(&r).Scale(2)` for us
+	r.Scale(2)
+	if r.width != 6 || r.height != 8 {
+		t.Errorf("original after Scale(2) = %vx%v, want 6x8", r.width, r.height)
+	}
+}
+
+// Pitfall 2: same as `measure(&r)` in `18-interfaces` chapter, only `*rect2` implements `geometry` and `Scaler`
+// (`var s Scaler = r` doesn't compile). The interface holds a pointer to `r`: scaling through it scales `r`.
+func TestScaleThroughInterface(t *testing.T) {
+	r := rect2{width: 3, height: 4}
+	var s Scaler = &r
+	s.Scale(2)
+	if r.area2() != 48 {
+		t.Errorf("area after Scale(2) through a Scaler = %v, want 48", r.area2())
+	}
+
+	// Whereas an interface holding a value holds a copy: neither it nor the original is affected.
+	// Fyi, `c` (an addressable variable) has `Scale` through `&c`, but `circle` itself doesn't: `var _ Scaler = c` doesn't compile.
+	c := circle{radius: 1}
+	var g geometry = c
+	gc := g.(circle)
+	gc.Scale(3)
+	if g.(circle).radius != 1 || c.radius != 1 {
+		t.Errorf("interface's value = %v, original = %v after scaling a copy, want both radius 1", g, c)
+	}
+}
+
+// Pitfall 3: the `range` variable is a copy of each element, scaling it doesn't scale the slice's elements
+func TestScaleRangeCopies(t *testing.T) {
+	rects := []rect2{{width: 1, height: 1}, {width: 2, height: 2}}
+	for _, r := range rects {
+		r.Scale(10)
+	}
+	if rects[0].width != 1 || rects[1].width != 2 {
+		t.Errorf("widths after scaling range copies = %v, %v, want 1, 2", rects[0].width, rects[1].width)
+	}
+	// Indexing gives the element itself (addressable)
+	for i := range rects {
+		rects[i].Scale(10)
+	}
+	if rects[0].width != 10 || rects[1].width != 20 {
+		t.Errorf("widths after scaling indexed elements = %v, %v, want 10, 20", rects[0].width, rects[1].width)
+	}
+}
+
+// Pitfall 4: map values are not addressable (the map may move them when it grows): `m["a"].Scale(2)` doesn't
+// compile. Either store pointers in the map, or take the value out, modify it & put it back.
+func TestScaleMapValues(t *testing.T) {
+	m := map[string]rect2{"a": {width: 1, height: 2}}
+	a := m["a"]
+	a.Scale(2)
+	if m["a"].width != 1 {
+		t.Errorf("map value = %v before being stored back, want width 1", m["a"])
+	}
+	m["a"] = a
+	if m["a"].width != 2 {
+		t.Errorf("map value = %v once stored back, want width 2", m["a"])
+	}
+}
+
+// Pitfall 5: a struct copy shares its slices with the original. The copies get their own vertices.
+func TestPolygonTransformedCopies(t *testing.T) {
+	p, _ := newPolygon(point{0, 0}, point{2, 0}, point{0, 2})
+	moved := p.Translated(5, 5).Rotated(math.Pi / 2).Scaled(2)
+	if p.vertices[1] != (point{2, 0}) {
+		t.Errorf("original vertex after transforming copies = %v, want {2 0}", p.vertices[1])
+	}
+	// Rotation & translation keep the area, scaling multiplies it by k²
+	if !almostEqual(moved.area2(), 4*p.area2()) {
+		t.Errorf("area = %v, want %v", moved.area2(), 4*p.area2())
+	}
+
+	var tr Transformer = &p
+	tr.Rotate(math.Pi)
+	if !almostEqual(p.vertices[1].x, -2) {
+		t.Errorf("vertex after Rotate(π) through a Transformer = %v, want {-2 0}", p.vertices[1])
+	}
+}
+
+func TestTransformedRect2(t *testing.T) {
+	r, _ := newRect2(2, 1)
+	tr := Transform(r)
+	turned := tr.Translated(1, 0).Rotated(math.Pi / 2)
+	if tr.Origin != (point{}) || tr.Angle != 0 {
+		t.Errorf("original after transforming a copy: at %v turned by %v, want unchanged", tr.Origin, tr.Angle)
+	}
+	// The rectangle itself isn't modified: it's still a 2x1 `rect2`
+	if turned.Shape != Drawable(r) || r.width != 2 {
+		t.Errorf("shape = %v, want the same 2x1 rectangle", turned.Shape)
+	}
+	// (1, 0)-(3, 0)-(3, 1)-(1, 1) turned a quarter around (0, 0): (0, 1)-(0, 3)-(-1, 3)-(-1, 1)
+	if b := turned.Bounds(); b.min.dist(point{-1, 1}) > 1e-9 || b.max.dist(point{0, 3}) > 1e-9 {
+		t.Errorf("Bounds() = %v, want {-1 1} to {0 3}", b)
+	}
+	if !turned.Contains(point{-0.5, 2}) || turned.Contains(point{1.5, 0.5}) {
+		t.Error("Contains: want (-0.5, 2) inside, and the old place (1.5, 0.5) outside")
+	}
+	if turned.area2() != r.area2() || turned.perim2() != r.perim2() {
+		t.Errorf("area %v & perimeter %v changed by the transforms", turned.area2(), turned.perim2())
+	}
+	// Turned by 45°, its bounding box is bigger than itself: `Placed` gives its exact outline
+	diamond := tr.Rotated(math.Pi / 4)
+	c, _ := newCircle(0.1)
+	if Intersects(diamond.Placed(), Place(c, 1.3, 0.2)) {
+		t.Error("Intersects: a circle outside the rotated rectangle, but inside its bounds, intersects it")
+	}
+	if !Intersects(diamond.Placed(), Place(c, 0.7, 1.5)) {
+		t.Error("Intersects: a circle inside the rotated rectangle doesn't intersect it")
+	}
+}
+
+func TestTransformedCircle(t *testing.T) {
+	c, _ := newCircle(1)
+	moved := Transform(c)
+	var tr Transformer = &moved
+	tr.Translate(2, 0)
+	tr.Rotate(math.Pi / 2)
+	tr.Scale(2)
+	if !almostEqual(moved.area2(), 4*math.Pi) || moved.Origin.dist(point{0, 4}) > 1e-9 {
+		t.Errorf("area %v at %v, want 4π at {0 4}", moved.area2(), moved.Origin)
+	}
+	if !moved.Contains(point{0, 5.5}) || moved.Contains(point{0, 0}) {
+		t.Error("Contains: want (0, 5.5) inside, and (0, 0) outside")
+	}
+	if c.radius != 1 {
+		t.Errorf("original radius = %v, want 1", c.radius)
+	}
+}
+
+// Apart from `polygon`, the catalogue's shapes can only be scaled: `Transformed` moves & rotates any of them
+func TestEveryShapeTransformable(t *testing.T) {
+	r, _ := newRect2(3, 4)
+	c, _ := newCircle(1)
+	sq, _ := newSquare(2)
+	tri, _ := newTriangle(3, 4, 5)
+	hex, _ := newRegularPolygon(6, 1)
+	e, _ := newEllipse(3, 1)
+	p, _ := newPolygon(point{0, 0}, point{2, 0}, point{0, 2})
+	for _, s := range []any{r, &c, &sq, &tri, &hex, &e} {
+		if _, ok := s.(Scaler); !ok {
+			t.Errorf("%T isn't a Scaler", s)
+		}
+		if _, ok := s.(Transformer); ok {
+			t.Errorf("%T is a Transformer: update the chapter's comment", s)
+		}
+	}
+	if _, ok := any(&p).(Transformer); !ok {
+		t.Errorf("%T isn't a Transformer", &p)
+	}
+
+	for _, d := range []Drawable{r, c, sq, tri, hex, e, p} {
+		// Moved away from its bounding box, then turned a half turn around (0, 0): its bounds are opposite
+		moved := Transform(d).Translated(100, 100).Rotated(math.Pi)
+		b, mb := d.Bounds(), moved.Bounds()
+		if mb.min.dist(point{-100 - b.max.x, -100 - b.max.y}) > 1e-6 || mb.max.dist(point{-100 - b.min.x, -100 - b.min.y}) > 1e-6 {
+			t.Errorf("%T: bounds %v moved & turned = %v", d, b, mb)
+		}
+		// A point inside (not on an edge, where rounding errors could put it outside), moved with it
+		q := point{b.min.x + 0.4*b.width(), b.min.y + 0.4*b.height()}
+		if !d.Contains(q) || !moved.Contains(point{-100 - q.x, -100 - q.y}) {
+			t.Errorf("%T: doesn't contain %v once moved", d, q)
+		}
+		if moved.area2() != d.area2() {
+			t.Errorf("%T: area %v, want %v", d, moved.area2(), d.area2())
+		}
+	}
+}
+
+// Transforms compose: rotating a quarter turn 4 times gives back the starting shape
+func TestQuarterTurns(t *testing.T) {
+	sq, _ := newSquare(2)
+	placed := Place(sq, 1, 0)
+	turned := placed
+	for range 4 {
+		turned.Rotate(math.Pi / 2)
+	}
+	if turned.Origin.dist(placed.Origin) > 1e-9 {
+		t.Errorf("origin after 4 quarter turns = %v, want %v", turned.Origin, placed.Origin)
+	}
+	// A rotated square becomes a polygon with the same area
+	if _, ok := turned.Shape.(polygon); !ok || !almostEqual(turned.area2(), placed.area2()) {
+		t.Errorf("rotated square = %T of area %v, want a polygon of area %v", turned.Shape, turned.area2(), placed.area2())
+	}
+}
+
+// Scaling multiplies the perimeter by k, the area by k²
+func TestScaleDrawable(t *testing.T) {
+	r, _ := newRect2(3, 4)
+	c, _ := newCircle(1)
+	sq, _ := newSquare(2)
+	e, _ := newEllipse(3, 1)
+	tri, _ := newTriangle(3, 4, 5)
+	hex, _ := newRegularPolygon(6, 1)
+	p, _ := newPolygon(point{0, 0}, point{2, 0}, point{0, 2})
+	for _, d := range []Drawable{r, c, sq, tri, hex, e, p, Place(sq, 1, 0), customShape{c}} {
+		scaled := scaleDrawable(d, 3)
+		if !almostEqual(scaled.area2(), 9*d.area2()) || !almostEqual(scaled.perim2(), 3*d.perim2()) {
+			t.Errorf("%T scaled by 3: area %v, perimeter %v, want %v and %v", d, scaled.area2(), scaled.perim2(), 9*d.area2(), 3*d.perim2())
+		}
+	}
+}
+
+// A `Drawable` unknown to the transforms is wrapped instead of being rejected
+func TestTransformUnknownDrawable(t *testing.T) {
+	c, _ := newCircle(1)
+	placed := Place(customShape{c}, 2, 0)
+	placed.Scale(2)
+	placed.Rotate(math.Pi / 2)
+	// A circle of radius 2 centred on (0, 4)
+	if !placed.Contains(point{0, 5.5}) || placed.Contains(point{4, 0}) {
+		t.Error("Contains: want (0, 5.5) inside, and (4, 0) outside")
+	}
+	if b := placed.Bounds(); !b.overlaps(box{point{-2, 2}, point{2, 6}}) || b.min.x > -2+1e-9 || b.max.y < 6-1e-9 {
+		t.Errorf("Bounds() = %v, want it to enclose {-2 2} to {2 6}", b)
+	}
+	if !almostEqual(placed.area2(), 4*math.Pi) {
+		t.Errorf("area = %v, want 4π", placed.area2())
+	}
+}
+
+// Scaling by 0 or a negative factor is a programming error
+func TestScaleInvalidFactor(t *testing.T) {
+	for _, k := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, ErrInvalidShape) {
+					t.Errorf("Scale(%v) panicked with %v, want an %v", k, err, ErrInvalidShape)
+				}
+			}()
+			r := rect2{width: 1, height: 1}
+			r.Scale(k)
+		}()
+	}
+}
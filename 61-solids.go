@@ -0,0 +1,178 @@
+// 3D shapes ("solids"): instead of an area & a perimeter, a volume & a surface area.
+//
+// Rather than writing each solid from scratch, most of them reuse the 2D shapes: a prism is a 2D shape (its base)
+// extruded along a height, like a cake cut out with a cookie cutter. Its volume is `base area * height`, and its
+// surface is the base twice (top & bottom) plus the sides (`base perimeter * height`), whatever the base is:
+//   - a cylinder is a prism with a `circle` base
+//   - a box is a prism with a `rect2` base (named `cuboid` here, as `box` is already the bounding box of `58-shape-rendering`)
+//
+// Both embed `prism` (see `20-struct-embedding` chapter): they get its methods, so they are `solid`s for free.
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+type solid interface {
+	volume() float64
+	surfaceArea() float64
+}
+
+// Any 2D shape extruded along `height`
+type prism struct {
+	base   geometry
+	height float64
+}
+
+func extrude(base geometry, height float64) (prism, error) {
+	if base == nil {
+		return prism{}, &ShapeError{Shape: "prism", Reason: "base is missing"}
+	}
+	// Shapes built without their constructor (or other `geometry` implementations) may have no area
+	if err := checkPositive("prism", shapeDim{"height", height}, shapeDim{"base area", base.area2()}); err != nil {
+		return prism{}, err
+	}
+	return prism{base: base, height: height}, nil
+}
+
+func (p prism) String() string {
+	return fmt.Sprintf("%v extruded by %g", p.base, p.height)
+}
+
+func (p prism) volume() float64 {
+	return p.base.area2() * p.height
+}
+
+func (p prism) surfaceArea() float64 {
+	return 2*p.base.area2() + p.base.perim2()*p.height
+}
+
+type cylinder struct {
+	prism
+}
+
+func newCylinder(radius, height float64) (cylinder, error) {
+	base, err := newCircle(radius)
+	if err != nil {
+		return cylinder{}, err
+	}
+	p, err := extrude(base, height)
+	return cylinder{p}, err
+}
+
+// Radius of its `circle` base (`c.base` is a field of the embedded `prism`, promoted to `cylinder`).
+// 0 for a cylinder built without `newCylinder` (ex: the zero value, whose base is nil).
+func (c cylinder) radius() float64 {
+	if base, ok := c.base.(circle); ok {
+		return base.radius
+	}
+	return 0
+}
+
+// The request's "box": a prism with a `rect2` base. Named `cuboid`, as `box` is already the bounding box
+// of `58-shape-rendering` chapter.
+type cuboid struct {
+	prism
+}
+
+func newCuboid(width, depth, height float64) (cuboid, error) {
+	base, err := newRect2(width, depth)
+	if err != nil {
+		return cuboid{}, err
+	}
+	p, err := extrude(base, height)
+	return cuboid{p}, err
+}
+
+// Solids that aren't extruded
+
+type sphere struct {
+	radius float64
+}
+
+func newSphere(radius float64) (sphere, error) {
+	if err := checkPositive("sphere", shapeDim{"radius", radius}); err != nil {
+		return sphere{}, err
+	}
+	return sphere{radius: radius}, nil
+}
+
+func (s sphere) volume() float64 {
+	return 4.0 / 3 * math.Pi * s.radius * s.radius * s.radius
+}
+
+func (s sphere) surfaceArea() float64 {
+	return 4 * math.Pi * s.radius * s.radius
+}
+
+// Circular base, tip above its centre
+type cone struct {
+	radius, height float64
+}
+
+func newCone(radius, height float64) (cone, error) {
+	if err := checkPositive("cone", shapeDim{"radius", radius}, shapeDim{"height", height}); err != nil {
+		return cone{}, err
+	}
+	return cone{radius: radius, height: height}, nil
+}
+
+// A third of the cylinder with the same base & height
+func (c cone) volume() float64 {
+	return math.Pi * c.radius * c.radius * c.height / 3
+}
+
+// Base + side. Unrolled, the side is a disc sector whose radius is the slant height (base edge to tip).
+func (c cone) surfaceArea() float64 {
+	slant := math.Hypot(c.radius, c.height)
+	return math.Pi*c.radius*c.radius + math.Pi*c.radius*slant
+}
+
+// Same as `measure` of `18-interfaces` chapter, for solids
+func measure3(s solid) {
+	fmt.Printf("%-45s volume %9.3f  surface %9.3f\n", fmt.Sprintf("%T%v", s, s), s.volume(), s.surfaceArea())
+}
+
+func solids_main() {
+	hex, _ := newRegularPolygon(6, 2)
+	tri, _ := newTriangle(3, 4, 5)
+	e, _ := newEllipse(3, 2)
+	solids := []solid{}
+	add := func(s solid, err error) {
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		solids = append(solids, s)
+	}
+	add(newCuboid(30, 20, 10))
+	add(newCylinder(3.3, 11.5))
+	add(newSphere(5))
+	add(newCone(3, 4))
+	// Any 2D shape can be extruded
+	add(extrude(hex, 5))
+	add(extrude(tri, 10))
+	add(extrude(e, 1))
+	for _, s := range solids {
+		measure3(s)
+	}
+
+	// Invalid solids
+	add(newCylinder(-1, 2))
+	add(newCuboid(1, 2, 0))
+	add(extrude(nil, 2))
+
+	// The embedded `prism`'s fields & methods are promoted
+	can, _ := newCylinder(3.3, 11.5)
+	fmt.Println("can height:", can.height, "- radius:", can.radius(), "- base area:", can.base.area2())
+
+	// Packaging: how many cans fit in a box? (each can takes a square of its diameter, standing up)
+	crate, _ := newCuboid(30, 20, 24)
+	w, d := crate.base.(*rect2).width, crate.base.(*rect2).height
+	perLayer := int(w/(2*can.radius())) * int(d/(2*can.radius()))
+	layers := int(crate.height / can.height)
+	cans := perLayer * layers
+	fmt.Printf("%d cans per crate (%d layers of %d), filling %.0f%% of it\n",
+		cans, layers, perLayer, 100*float64(cans)*can.volume()/crate.volume())
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// 2D & 3D formulas agree with each other
+
+func TestCube(t *testing.T) {
+	cube, _ := newCuboid(2, 2, 2)
+	if cube.volume() != 8 || cube.surfaceArea() != 24 {
+		t.Errorf("cube of side 2: volume %g, surface %g, want side³ = 8 and 6 * side² = 24", cube.volume(), cube.surfaceArea())
+	}
+	sq, _ := newSquare(2)
+	sqPrism, _ := extrude(sq, 2)
+	if sqPrism.volume() != cube.volume() || sqPrism.surfaceArea() != cube.surfaceArea() {
+		t.Errorf("prism with a square base: volume %g, surface %g, want the cube's", sqPrism.volume(), sqPrism.surfaceArea())
+	}
+}
+
+func TestConeIsAThirdOfCylinder(t *testing.T) {
+	c, _ := newCylinder(2, 3)
+	k, _ := newCone(2, 3)
+	if !almostEqual(3*k.volume(), c.volume()) {
+		t.Errorf("cone volume %g, want cylinder volume / 3 = %g", k.volume(), c.volume()/3)
+	}
+}
+
+// A sphere's surface is the derivative of its volume (a thin layer added around it)
+func TestSphereSurfaceIsVolumeDerivative(t *testing.T) {
+	s1, _ := newSphere(2)
+	s2, _ := newSphere(2 + 1e-6)
+	if d := (s2.volume() - s1.volume()) / 1e-6; math.Abs(d-s1.surfaceArea()) >= 1e-4 {
+		t.Errorf("d(volume)/d(radius) = %g, surface = %g", d, s1.surfaceArea())
+	}
+}
+
+// A cylinder's volume is between the volumes of prisms with inscribed & circumscribed regular polygon bases
+func TestCylinderBetweenPrisms(t *testing.T) {
+	c, _ := newCylinder(2, 3)
+	inner, _ := newRegularPolygon(64, 2*2*math.Sin(math.Pi/64))
+	outer, _ := newRegularPolygon(64, 2*2*math.Tan(math.Pi/64))
+	innerPrism, _ := extrude(inner, 3)
+	outerPrism, _ := extrude(outer, 3)
+	if !(innerPrism.volume() < c.volume() && c.volume() < outerPrism.volume()) {
+		t.Errorf("volumes: inscribed prism %g, cylinder %g, circumscribed prism %g", innerPrism.volume(), c.volume(), outerPrism.volume())
+	}
+}
+
+func TestCylinderRadius(t *testing.T) {
+	c, _ := newCylinder(3.3, 1)
+	if c.radius() != 3.3 {
+		t.Errorf("radius = %g, want 3.3", c.radius())
+	}
+	// No `circle` base: no panic
+	sq, _ := newSquare(2)
+	squareBased, _ := extrude(sq, 1)
+	for _, c := range []cylinder{{}, {squareBased}} {
+		if r := c.radius(); r != 0 {
+			t.Errorf("radius of %v = %g, want 0", c, r)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+// The dimensions of our shapes are bare `float64`s: nothing says whether `circle{radius: 5}` is in cm or in inches,
+// and adding a rectangle's area (cm²) to a circle's perimeter (inches) compiles just fine.
+//
+// Here we give quantities their own types, the way `time.Duration` does for durations:
+//   - `Length`, `Area` and `Volume` are distinct types (all `float64` underneath, see `19-enums` chapter for named types):
+//     adding an `Area` to a `Length` doesn't compile anymore
+//   - units are constants of those types (`Centimetre`, `Inch`, etc.), like `time.Second`: `3.5 * Centimetre`
+//   - every quantity is stored in the same base unit (metre, m², m³), so adding cm to inches is correct
+//
+// Fyi, Go has no operator overloading: `Length * Length` is still a `Length` (and not an `Area`), exactly like
+// `time.Second * time.Second` is a (meaningless) `time.Duration`. Hence the `Times` methods to go up a dimension.
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// In metres
+type Length float64
+
+// In square metres
+type Area float64
+
+// In cubic metres
+type Volume float64
+
+const (
+	Millimetre Length = 0.001
+	Centimetre Length = 0.01
+	Metre      Length = 1
+	Inch       Length = 0.0254
+	Foot       Length = 0.3048
+)
+
+// Symbol of each unit, from the smallest to the biggest metric one
+var lengthUnits = []struct {
+	symbol string
+	size   Length
+}{
+	{"mm", Millimetre},
+	{"cm", Centimetre},
+	{"m", Metre},
+	{"in", Inch},
+	{"ft", Foot},
+}
+
+// Symbol of `unit`, if it's one of `lengthUnits` (any `Length` can be used as a unit: `10 * Centimetre` too)
+func unitSymbol(unit Length) (string, bool) {
+	for _, u := range lengthUnits {
+		if u.size == unit {
+			return u.symbol, true
+		}
+	}
+	return "", false
+}
+
+// Biggest metric unit in which `v` (in base unit, of dimension `dim`) is >= 1, or mm for tiny values.
+// Only metric units are picked automatically, as mixing them with imperial ones would be surprising.
+func bestUnit(v float64, dim int) Length {
+	unit := Millimetre
+	for _, u := range []Length{Centimetre, Metre} {
+		if math.Abs(v) >= math.Pow(float64(u), float64(dim)) {
+			unit = u
+		}
+	}
+	return unit
+}
+
+// Value of `v` (in base unit, of dimension `dim`) expressed in `unit`, followed by the unit's symbol.
+// A unit without a symbol can't be written: the value is expressed in metres instead.
+func formatQuantity(v float64, unit Length, dim int) string {
+	symbol, ok := unitSymbol(unit)
+	if !ok {
+		unit, symbol = Metre, "m"
+	}
+	value := v / math.Pow(float64(unit), float64(dim))
+	// 6 significant digits are enough, and hide rounding errors (`0.1 * 3` = 0.30000000000000004)
+	s := strconv.FormatFloat(value, 'g', 6, 64) + symbol
+	switch dim {
+	case 2:
+		s += "²"
+	case 3:
+		s += "³"
+	}
+	return s
+}
+
+// Quantity of dimension `dim` from a string like `"3.5cm"`, `"2 in"`, `"12cm²"` or `"12cm2"`
+func parseQuantity(s string, dim int) (float64, error) {
+	s = strings.TrimSpace(s)
+	isLetter := func(r rune) bool { return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') }
+	// The unit is the letters at the end, possibly followed by a power (`²`, `³`, or a digit right after a letter).
+	// Scanned from the end, as the number can contain letters too (`1e3mm`).
+	end := len(s)
+	if r, size := utf8.DecodeLastRuneInString(s); r == '²' || r == '³' || (r >= '0' && r <= '9') {
+		if prev, _ := utf8.DecodeLastRuneInString(s[:end-size]); isLetter(prev) {
+			end -= size
+		}
+	}
+	i := strings.LastIndexFunc(s[:end], func(r rune) bool { return !isLetter(r) }) + 1
+	if i == 0 || i == end {
+		return 0, fmt.Errorf("invalid quantity %q: expected a number followed by a unit", s)
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(s[:i]), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q: %w", s, err)
+	}
+
+	symbol := s[i:]
+	for _, suffix := range [][2]string{{"²", "2"}, {"³", "3"}} {
+		symbol = strings.Replace(symbol, suffix[0], suffix[1], 1)
+	}
+	if dim > 1 {
+		var ok bool
+		symbol, ok = strings.CutSuffix(symbol, strconv.Itoa(dim))
+		if !ok {
+			return 0, fmt.Errorf("invalid quantity %q: unit must be raised to the power %d", s, dim)
+		}
+	}
+	for _, u := range lengthUnits {
+		if u.symbol == symbol {
+			return value * math.Pow(float64(u.size), float64(dim)), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid quantity %q: unknown unit %q", s, symbol)
+}
+
+func ParseLength(s string) (Length, error) {
+	v, err := parseQuantity(s, 1)
+	return Length(v), err
+}
+
+func ParseArea(s string) (Area, error) {
+	v, err := parseQuantity(s, 2)
+	return Area(v), err
+}
+
+func ParseVolume(s string) (Volume, error) {
+	v, err := parseQuantity(s, 3)
+	return Volume(v), err
+}
+
+// Value expressed in `unit` (ex: `l.In(Inch)`)
+func (l Length) In(unit Length) float64 {
+	return float64(l / unit)
+}
+
+func (l Length) Format(unit Length) string {
+	return formatQuantity(float64(l), unit, 1)
+}
+
+// `fmt.Stringer`: `fmt.Println(l)` prints `3.5cm` instead of `0.035`
+func (l Length) String() string {
+	return l.Format(bestUnit(float64(l), 1))
+}
+
+func (l Length) Times(o Length) Area {
+	return Area(float64(l) * float64(o))
+}
+
+// Value expressed in square `unit` (ex: `a.In(Foot)` for square feet)
+func (a Area) In(unit Length) float64 {
+	return float64(a) / float64(unit*unit)
+}
+
+func (a Area) Format(unit Length) string {
+	return formatQuantity(float64(a), unit, 2)
+}
+
+func (a Area) String() string {
+	return a.Format(bestUnit(float64(a), 2))
+}
+
+func (a Area) Times(l Length) Volume {
+	return Volume(float64(a) * float64(l))
+}
+
+// Value expressed in cubic `unit`
+func (v Volume) In(unit Length) float64 {
+	return float64(v) / float64(unit*unit*unit)
+}
+
+func (v Volume) Format(unit Length) string {
+	return formatQuantity(float64(v), unit, 3)
+}
+
+func (v Volume) String() string {
+	return v.Format(bestUnit(float64(v), 3))
+}
+
+// Litres are cubic decimetres
+func (v Volume) Litres() float64 {
+	return v.In(10 * Centimetre)
+}
+
+// Shapes with units
+
+// Same as `geometry`, with typed quantities
+type measuredGeometry interface {
+	area2() Area
+	perim2() Length
+}
+
+type measuredSolid interface {
+	volume() Volume
+	surfaceArea() Area
+}
+
+// A shape whose dimensions are in `unit`
+type measured struct {
+	shape geometry
+	unit  Length
+}
+
+func InUnits(shape geometry, unit Length) measured {
+	return measured{shape: shape, unit: unit}
+}
+
+// Scaling a shape by k multiplies its perimeter by k and its area by k² (see `56-shape-catalogue` chapter)
+func (m measured) area2() Area {
+	return Area(m.shape.area2() * float64(m.unit*m.unit))
+}
+
+func (m measured) perim2() Length {
+	return Length(m.shape.perim2() * float64(m.unit))
+}
+
+func (m measured) String() string {
+	symbol, ok := unitSymbol(m.unit)
+	if !ok {
+		symbol = "units of " + m.unit.String()
+	}
+	return fmt.Sprintf("%v in %s", m.shape, symbol)
+}
+
+type measured3 struct {
+	shape solid
+	unit  Length
+}
+
+func SolidInUnits(shape solid, unit Length) measured3 {
+	return measured3{shape: shape, unit: unit}
+}
+
+func (m measured3) volume() Volume {
+	return Volume(m.shape.volume() * float64(m.unit*m.unit*m.unit))
+}
+
+func (m measured3) surfaceArea() Area {
+	return Area(m.shape.surfaceArea() * float64(m.unit*m.unit))
+}
+
+// Compile-time checks that the wrappers implement the typed interfaces
+var (
+	_ measuredGeometry = measured{}
+	_ measuredSolid    = measured3{}
+)
+
+// Builds shapes from typed dimensions, stored in metres.
+// On error, the zero `measured` is returned (not one wrapping a nil `*rect2`, whose methods would panic).
+func newMeasuredRect(width, height Length) (measured, error) {
+	r, err := newRect2(width.In(Metre), height.In(Metre))
+	if err != nil {
+		return measured{}, err
+	}
+	return InUnits(r, Metre), nil
+}
+
+func newMeasuredCircle(radius Length) (measured, error) {
+	c, err := newCircle(radius.In(Metre))
+	if err != nil {
+		return measured{}, err
+	}
+	return InUnits(c, Metre), nil
+}
+
+func units_main() {
+	fmt.Println(3.5*Centimetre, 2*Inch, 1500*Millimetre, 3*Foot)
+	fmt.Println((2 * Inch).In(Centimetre), Metre.In(Foot))
+	fmt.Println((12 * Inch).Format(Foot), (0.5 * Metre).Format(Millimetre))
+	// No symbol for a unit of 10cm: written in metres
+	fmt.Println((3 * Metre).Format(10 * Centimetre))
+
+	for _, s := range []string{"3.5cm", "2 in", "-1.5ft", "1e3mm", "7", "3.5 parsecs", "cm"} {
+		l, err := ParseLength(s)
+		fmt.Printf("%-12q %v %v\n", s, l, err)
+	}
+	fmt.Println(ParseArea("12cm²"))
+	fmt.Println(ParseArea("1ft2"))
+	fmt.Println(ParseArea("12cm"))
+	fmt.Println(ParseVolume("2m³"))
+
+	// A rectangle in cm and a circle in inches can be summed correctly
+	r, _ := newRect2(30, 20)
+	c, _ := newCircle(4)
+	rCm, cIn := InUnits(r, Centimetre), InUnits(c, Inch)
+	fmt.Println(rCm, "area:", rCm.area2(), "- perimeter:", rCm.perim2())
+	fmt.Println(cIn, "area:", cIn.area2(), "- perimeter:", cIn.perim2())
+	fmt.Println("total area:", rCm.area2()+cIn.area2(), "=", (rCm.area2() + cIn.area2()).Format(Inch))
+	// Doesn't compile anymore: mismatched types Area and Length
+	// fmt.Println(rCm.area2() + cIn.perim2())
+
+	width, _ := ParseLength("3.5cm")
+	height, _ := ParseLength("2in")
+	label, _ := newMeasuredRect(width, height)
+	fmt.Println("label area:", label.area2())
+	_, err := newMeasuredCircle(-1 * Centimetre)
+	fmt.Println(err)
+
+	// Solids: the can of `61-solids` chapter, in cm
+	can, _ := newCylinder(3.3, 11.5)
+	canCm := SolidInUnits(can, Centimetre)
+	fmt.Printf("can: %v (%.2fL), surface %v\n", canCm.volume(), canCm.volume().Litres(), canCm.surfaceArea())
+	// Going up a dimension explicitly
+	fmt.Println("label area * 10cm:", label.area2().Times(10*Centimetre))
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseLength(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want Length
+	}{
+		{"3.5cm", 3.5 * Centimetre},
+		{"2 in", 2 * Inch},
+		{"-1.5ft", -1.5 * Foot},
+		// The exponent's `e` isn't part of the unit
+		{"1e3mm", Metre},
+		{"2.5E-2 m", 2.5 * Centimetre},
+	} {
+		got, err := ParseLength(tc.in)
+		if err != nil || math.Abs(float64(got-tc.want)) > 1e-12 {
+			t.Errorf("ParseLength(%q) = %v, %v, want %v", tc.in, got, err, tc.want)
+		}
+	}
+	for _, in := range []string{"7", "1e3", "cm", "3.5 parsecs", "12cm2", ""} {
+		if got, err := ParseLength(in); err == nil {
+			t.Errorf("ParseLength(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestParseAreaAndVolume(t *testing.T) {
+	for _, in := range []string{"12cm²", "12cm2", "1.2e1 cm2"} {
+		if got, err := ParseArea(in); err != nil || math.Abs(float64(got-12e-4)) > 1e-12 {
+			t.Errorf("ParseArea(%q) = %v, %v, want 12cm²", in, got, err)
+		}
+	}
+	if got, err := ParseArea("12cm"); err == nil {
+		t.Errorf("ParseArea(12cm) = %v, want an error", got)
+	}
+	if got, err := ParseVolume("2m³"); err != nil || got != 2 {
+		t.Errorf("ParseVolume(2m³) = %v, %v, want 2m³", got, err)
+	}
+}
+
+// Any `Length` can be a unit: the ones without a symbol are written in metres
+func TestFormatUnknownUnit(t *testing.T) {
+	if got := (3 * Metre).Format(10 * Centimetre); got != "3m" {
+		t.Errorf("Format(10cm) = %q, want 3m", got)
+	}
+	if got := Area(2).Format(2 * Metre); got != "2m²" {
+		t.Errorf("Format(2m) = %q, want 2m²", got)
+	}
+	r, _ := newRect2(3, 4)
+	if got := InUnits(r, 2*Metre).String(); !strings.HasSuffix(got, " in units of 2m") {
+		t.Errorf("InUnits(r, 2m) = %q, want it in units of 2m", got)
+	}
+}
+
+// `a` and `b` are equal up to rounding errors
+func sameQuantity[Q ~float64](a, b Q) bool {
+	return almostEqual(float64(a), float64(b))
+}
+
+func TestMeasuredConversions(t *testing.T) {
+	r, _ := newRect2(30, 20)
+	var rCm measuredGeometry = InUnits(r, Centimetre)
+	if a := rCm.area2(); !sameQuantity(a, 600*Centimetre.Times(Centimetre)) || !almostEqual(a.In(Metre), 0.06) ||
+		!almostEqual(a.In(Inch), 600/(2.54*2.54)) || a.String() != "600cm²" || a.Format(Millimetre) != "60000mm²" {
+		t.Errorf("30cm x 20cm area = %v (%gm², %gin²)", a, a.In(Metre), a.In(Inch))
+	}
+	if p := rCm.perim2(); !sameQuantity(p, Metre) || !almostEqual(p.In(Inch), 100/2.54) || p.String() != "1m" || p.Format(Centimetre) != "100cm" {
+		t.Errorf("30cm x 20cm perimeter = %v (%gin)", p, p.In(Inch))
+	}
+
+	// The same shape in another unit: the area scales by the square of the ratio between units
+	rIn := InUnits(r, Inch)
+	if ratio := float64(rIn.area2() / rCm.area2()); !almostEqual(ratio, 2.54*2.54) {
+		t.Errorf("in² / cm² = %g, want %g", ratio, 2.54*2.54)
+	}
+	if got := rIn.perim2().Format(Inch); got != "100in" {
+		t.Errorf("perimeter in inches = %q, want 100in", got)
+	}
+	if got := rIn.String(); !strings.HasSuffix(got, " in in") {
+		t.Errorf("String() = %q, want the shape in inches", got)
+	}
+
+	c, _ := newCircle(4)
+	cIn := InUnits(c, Inch)
+	if p := cIn.perim2(); !almostEqual(p.In(Inch), 8*math.Pi) || p.String() != "63.8372cm" {
+		t.Errorf("circle of radius 4in: perimeter %v (%gin)", p, p.In(Inch))
+	}
+	if got := cIn.String(); got != "{4} in in" {
+		t.Errorf("String() = %q, want {4} in in", got)
+	}
+
+	can, _ := newCylinder(3.3, 11.5)
+	var canCm measuredSolid = SolidInUnits(can, Centimetre)
+	if v := canCm.volume(); !almostEqual(v.Litres(), can.volume()/1000) || v.String() != "393.437cm³" {
+		t.Errorf("can volume = %v (%gL)", v, v.Litres())
+	}
+	if s := canCm.surfaceArea(); !almostEqual(s.In(Centimetre), can.surfaceArea()) {
+		t.Errorf("can surface = %v, want %gcm²", s, can.surfaceArea())
+	}
+}
+
+func TestNewMeasuredInvalid(t *testing.T) {
+	// The zero value on error, not a shape wrapping a nil `*rect2`
+	if m, err := newMeasuredRect(-1*Centimetre, 2*Centimetre); !errors.Is(err, ErrInvalidShape) || m != (measured{}) {
+		t.Errorf("newMeasuredRect(-1cm, 2cm) = %v, %v, want the zero value and an invalid shape error", m, err)
+	}
+	if m, err := newMeasuredCircle(0); !errors.Is(err, ErrInvalidShape) || m != (measured{}) {
+		t.Errorf("newMeasuredCircle(0) = %v, %v, want the zero value and an invalid shape error", m, err)
+	}
+	if m, err := newMeasuredRect(3.5*Centimetre, 2*Inch); err != nil || !sameQuantity(m.area2(), (3.5*Centimetre).Times(2*Inch)) {
+		t.Errorf("newMeasuredRect(3.5cm, 2in) = %v, %v", m, err)
+	}
+}
@@ -0,0 +1,325 @@
+// A `shapes` command: reads a list of shapes (the JSON format of `57-shape-serialization` chapter) and reports
+// statistics about them: totals, per type, largest/smallest, a histogram of the areas and a sorted listing.
+//
+// This package has a single `main` (see `40-mutexes` chapter), so the command is a function taking its arguments,
+// input & output explicitly, instead of reading `os.Args`/`os.Stdin` & writing to `os.Stdout` directly.
+// Bonus: it can be run on any input & its output checked (as done in `shapes_command_main`), no terminal needed.
+// `main` hands it `os.Args` & co when its 1st argument is `shapes`:
+//
+//	go run . shapes [-format table|csv|json] [-sort area|perimeter|type|input] [-desc] [-bins n] [file]
+//
+// Flags are parsed with `flag.FlagSet` (the `flag` package's functions use a global set, parsing `os.Args`).
+//
+// See `63-shapes-command_test.go`: its outputs are compared to the expected ones in `testdata/shapes-command`
+// ("golden files", rewritten with `go test -run ShapesCommand -update` after an intended change).
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+type shapeEntry struct {
+	Index       int     `json:"index"`
+	Type        string  `json:"type"`
+	Description string  `json:"description"`
+	Area        float64 `json:"area"`
+	Perimeter   float64 `json:"perimeter"`
+}
+
+type typeStats struct {
+	Type      string  `json:"type"`
+	Count     int     `json:"count"`
+	Area      float64 `json:"area"`
+	Perimeter float64 `json:"perimeter"`
+}
+
+type histogramBin struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+type shapesReport struct {
+	Count          int            `json:"count"`
+	TotalArea      float64        `json:"totalArea"`
+	TotalPerimeter float64        `json:"totalPerimeter"`
+	ByType         []typeStats    `json:"byType"`
+	Largest        shapeEntry     `json:"largest"`
+	Smallest       shapeEntry     `json:"smallest"`
+	Histogram      []histogramBin `json:"histogram"`
+	Shapes         []shapeEntry   `json:"shapes"`
+}
+
+// Type name & dimensions of a shape, with a type switch (like `detectCircle` does with a type assertion)
+func describeShape(g geometry) (kind, description string) {
+	switch s := g.(type) {
+	case *rect2:
+		return "rectangle", fmt.Sprintf("%gx%g", s.width, s.height)
+	case circle:
+		return "circle", fmt.Sprintf("r=%g", s.radius)
+	case square:
+		return "square", fmt.Sprintf("side=%g", s.side)
+	case triangle:
+		return "triangle", fmt.Sprintf("sides=%g,%g,%g", s.a, s.b, s.c)
+	case regularPolygon:
+		return "regular_polygon", fmt.Sprintf("%d sides of %g", s.sides, s.side)
+	case polygon:
+		return "polygon", fmt.Sprintf("%d vertices", len(s.vertices))
+	case ellipse:
+		return "ellipse", fmt.Sprintf("a=%g b=%g", s.a, s.b)
+	default:
+		// Shapes from elsewhere: the Go type name at least
+		return fmt.Sprintf("%T", g), fmt.Sprint(g)
+	}
+}
+
+// `bins` equal-width bins between the smallest and largest areas
+func areaHistogram(entries []shapeEntry, bins int) []histogramBin {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, e := range entries {
+		lo, hi = min(lo, e.Area), max(hi, e.Area)
+	}
+	// All areas equal: a single bin
+	if hi == lo {
+		bins = 1
+	}
+	width := (hi - lo) / float64(bins)
+
+	histogram := make([]histogramBin, bins)
+	for i := range histogram {
+		histogram[i] = histogramBin{From: lo + float64(i)*width, To: lo + float64(i+1)*width}
+	}
+	histogram[bins-1].To = hi
+	for _, e := range entries {
+		i := bins - 1
+		if width > 0 {
+			// The largest area falls on the last bin's upper edge: it belongs to the last bin
+			i = min(int((e.Area-lo)/width), bins-1)
+		}
+		histogram[i].Count++
+	}
+	return histogram
+}
+
+func newShapesReport(shapes []geometry, bins int) shapesReport {
+	report := shapesReport{Count: len(shapes)}
+	byType := map[string]*typeStats{}
+	for i, g := range shapes {
+		kind, description := describeShape(g)
+		e := shapeEntry{Index: i, Type: kind, Description: description, Area: g.area2(), Perimeter: g.perim2()}
+		report.Shapes = append(report.Shapes, e)
+		report.TotalArea += e.Area
+		report.TotalPerimeter += e.Perimeter
+
+		stats, ok := byType[kind]
+		if !ok {
+			stats = &typeStats{Type: kind}
+			byType[kind] = stats
+		}
+		stats.Count++
+		stats.Area += e.Area
+		stats.Perimeter += e.Perimeter
+	}
+
+	for _, stats := range byType {
+		report.ByType = append(report.ByType, *stats)
+	}
+	// Map iteration order is random: sort for a stable output (biggest total area first)
+	slices.SortFunc(report.ByType, func(a, b typeStats) int {
+		return cmp.Or(cmp.Compare(b.Area, a.Area), strings.Compare(a.Type, b.Type))
+	})
+
+	report.Largest = slices.MaxFunc(report.Shapes, func(a, b shapeEntry) int { return cmp.Compare(a.Area, b.Area) })
+	report.Smallest = slices.MinFunc(report.Shapes, func(a, b shapeEntry) int { return cmp.Compare(a.Area, b.Area) })
+	report.Histogram = areaHistogram(report.Shapes, bins)
+	return report
+}
+
+// Orders of the listing, by `-sort` flag value
+var shapeOrders = map[string]func(a, b shapeEntry) int{
+	"input":     func(a, b shapeEntry) int { return cmp.Compare(a.Index, b.Index) },
+	"area":      func(a, b shapeEntry) int { return cmp.Compare(a.Area, b.Area) },
+	"perimeter": func(a, b shapeEntry) int { return cmp.Compare(a.Perimeter, b.Perimeter) },
+	"type": func(a, b shapeEntry) int {
+		return cmp.Or(strings.Compare(a.Type, b.Type), cmp.Compare(a.Area, b.Area))
+	},
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 3, 64)
+}
+
+func writeShapesTable(w io.Writer, report shapesReport) error {
+	// Each section is aligned on its own: a tabwriter aligns the cells separated by tabs (here with 2 spaces of padding)
+	sections := []func(tw *tabwriter.Writer){
+		func(tw *tabwriter.Writer) {
+			fmt.Fprintf(tw, "shapes\t%d\n", report.Count)
+			fmt.Fprintf(tw, "total area\t%s\n", formatFloat(report.TotalArea))
+			fmt.Fprintf(tw, "total perimeter\t%s\n", formatFloat(report.TotalPerimeter))
+			for _, e := range []struct {
+				name  string
+				entry shapeEntry
+			}{{"largest", report.Largest}, {"smallest", report.Smallest}} {
+				fmt.Fprintf(tw, "%s\t%s (#%d %s %s)\n", e.name, formatFloat(e.entry.Area), e.entry.Index, e.entry.Type, e.entry.Description)
+			}
+		},
+		func(tw *tabwriter.Writer) {
+			fmt.Fprintln(tw, "type\tcount\tarea\tperimeter")
+			for _, s := range report.ByType {
+				fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", s.Type, s.Count, formatFloat(s.Area), formatFloat(s.Perimeter))
+			}
+		},
+		func(tw *tabwriter.Writer) {
+			fmt.Fprintln(tw, "area from\tto\tcount")
+			most := slices.MaxFunc(report.Histogram, func(a, b histogramBin) int { return cmp.Compare(a.Count, b.Count) }).Count
+			for _, bin := range report.Histogram {
+				// Bar of up to 30 characters, proportional to the bin's count
+				bar := strings.Repeat("#", bin.Count*30/max(most, 1))
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", formatFloat(bin.From), formatFloat(bin.To), bin.Count, bar)
+			}
+		},
+		func(tw *tabwriter.Writer) {
+			fmt.Fprintln(tw, "#\ttype\tdescription\tarea\tperimeter")
+			for _, e := range report.Shapes {
+				fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", e.Index, e.Type, e.Description, formatFloat(e.Area), formatFloat(e.Perimeter))
+			}
+		},
+	}
+
+	for i, section := range sections {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		section(tw)
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CSV is a single table: the listing only, which spreadsheets can total & chart by themselves
+func writeShapesCSV(w io.Writer, report shapesReport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"index", "type", "description", "area", "perimeter"})
+	for _, e := range report.Shapes {
+		cw.Write([]string{strconv.Itoa(e.Index), e.Type, e.Description, formatFloat(e.Area), formatFloat(e.Perimeter)})
+	}
+	// Errors of `Write` are kept until `Flush`, and returned by `Error`
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeShapesJSON(w io.Writer, report shapesReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+var shapesWriters = map[string]func(io.Writer, shapesReport) error{
+	"table": writeShapesTable,
+	"csv":   writeShapesCSV,
+	"json":  writeShapesJSON,
+}
+
+// The `shapes` command: reads shapes from the file given as argument (or from `stdin` if none, or `-`)
+func shapesCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("shapes", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "table", "output format: table, csv or json")
+	order := flags.String("sort", "area", "listing order: area, perimeter, type or input")
+	desc := flags.Bool("desc", false, "sort the listing in descending order")
+	bins := flags.Int("bins", 5, "number of bins of the area histogram")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: shapes [flags] [file]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		// `-help` isn't a failure: the usage was printed as asked
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	write, ok := shapesWriters[*format]
+	if !ok {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	compare, ok := shapeOrders[*order]
+	if !ok {
+		return fmt.Errorf("unknown sort order %q", *order)
+	}
+	if *bins < 1 {
+		return fmt.Errorf("invalid number of bins %d", *bins)
+	}
+
+	var shapes ShapeList
+	switch path := flags.Arg(0); {
+	case flags.NArg() > 1:
+		return errors.New("too many arguments: expected at most 1 file")
+	case path == "" || path == "-":
+		if err := json.NewDecoder(stdin).Decode(&shapes); err != nil {
+			return fmt.Errorf("reading shapes: %w", err)
+		}
+	default:
+		var err error
+		if shapes, err = LoadShapes(path); err != nil {
+			return err
+		}
+	}
+	if len(shapes) == 0 {
+		return errors.New("no shapes")
+	}
+
+	report := newShapesReport(shapes, *bins)
+	if *desc {
+		// Not a reversed ascending sort, which would reverse the order of ties too
+		ascending := compare
+		compare = func(a, b shapeEntry) int { return ascending(b, a) }
+	}
+	// Stable: shapes comparing equal keep their input order
+	slices.SortStableFunc(report.Shapes, compare)
+	return write(stdout, report)
+}
+
+func shapes_command_main() {
+	input := `[
+		{"type": "rectangle", "width": 3, "height": 4},
+		{"type": "circle", "radius": 5},
+		{"type": "circle", "radius": 1},
+		{"type": "square", "side": 2},
+		{"type": "triangle", "a": 3, "b": 4, "c": 5},
+		{"type": "regular_polygon", "sides": 6, "side": 2},
+		{"type": "polygon", "vertices": [[0, 0], [4, 0], [4, 4], [2, 2], [0, 4]]},
+		{"type": "ellipse", "a": 5, "b": 3},
+		{"type": "rectangle", "width": 10, "height": 0.5}
+	]`
+
+	run := func(args ...string) {
+		fmt.Println("$ shapes", strings.Join(args, " "))
+		if err := shapesCommand(args, strings.NewReader(input), os.Stdout, os.Stdout); err != nil {
+			fmt.Println("error:", err)
+		}
+		fmt.Println()
+	}
+	run()
+	run("-format", "csv", "-sort", "type")
+	run("-format", "json", "-sort", "perimeter", "-desc", "-bins", "2")
+	run("-format", "yaml")
+	run("missing.json")
+	run("-help")
+}
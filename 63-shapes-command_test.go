@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of testdata/ with the current outputs")
+
+// 2 rectangles & 2 squares with equal areas, to check the order of ties
+const shapesCommandInput = `[
+	{"type": "rectangle", "width": 3, "height": 4},
+	{"type": "circle", "radius": 5},
+	{"type": "square", "side": 2},
+	{"type": "rectangle", "width": 2, "height": 6},
+	{"type": "triangle", "a": 3, "b": 4, "c": 5},
+	{"type": "square", "side": 2},
+	{"type": "ellipse", "a": 5, "b": 3}
+]`
+
+// Compares `got` to the content of testdata/shapes-command/`name`.golden
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "shapes-command", name+".golden")
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
+
+func TestShapesCommandGolden(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		args []string
+	}{
+		{"table", nil},
+		{"table-bins", []string{"-bins", "2"}},
+		{"csv-input", []string{"-format", "csv", "-sort", "input"}},
+		{"csv-area-desc", []string{"-format", "csv", "-desc"}},
+		{"csv-perimeter", []string{"-format", "csv", "-sort", "perimeter"}},
+		{"csv-type-desc", []string{"-format", "csv", "-sort", "type", "-desc"}},
+		{"json", []string{"-format", "json", "-sort", "type"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if err := shapesCommand(c.args, strings.NewReader(shapesCommandInput), &stdout, &stderr); err != nil {
+				t.Fatalf("shapes %v: %v\n%s", c.args, err, stderr.String())
+			}
+			if stderr.Len() > 0 {
+				t.Errorf("shapes %v wrote to stderr: %s", c.args, stderr.String())
+			}
+			checkGolden(t, c.name, stdout.Bytes())
+		})
+	}
+}
+
+// Shapes with the same area keep their input order, in both directions
+func TestShapesCommandDescTies(t *testing.T) {
+	for _, args := range [][]string{{"-format", "csv"}, {"-format", "csv", "-desc"}} {
+		var stdout bytes.Buffer
+		if err := shapesCommand(args, strings.NewReader(shapesCommandInput), &stdout, &stdout); err != nil {
+			t.Fatal(err)
+		}
+		out := stdout.String()
+		// The 2 squares (#2, #5) and the 2 rectangles (#0, #3)
+		for _, pair := range [][2]string{{"\n2,square", "\n5,square"}, {"\n0,rectangle", "\n3,rectangle"}} {
+			if i, j := strings.Index(out, pair[0]), strings.Index(out, pair[1]); i < 0 || j < i {
+				t.Errorf("shapes %v: %q not before %q:\n%s", args, pair[0][1:], pair[1][1:], out)
+			}
+		}
+	}
+}
+
+func TestShapesCommandFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shapes.json")
+	if err := os.WriteFile(path, []byte(shapesCommandInput), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Same output from the file as from stdin
+	for _, args := range [][]string{{"-format", "csv", path}, {"-format", "csv", "-"}} {
+		var stdout bytes.Buffer
+		if err := shapesCommand(args, strings.NewReader(shapesCommandInput), &stdout, &stdout); err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, "csv-area", stdout.Bytes())
+	}
+}
+
+func TestShapesCommandErrors(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		args  []string
+		input string
+		// Contained in the returned error
+		want string
+	}{
+		{"unknown format", []string{"-format", "yaml"}, shapesCommandInput, `unknown format "yaml"`},
+		{"unknown order", []string{"-sort", "colour"}, shapesCommandInput, `unknown sort order "colour"`},
+		{"no bins", []string{"-bins", "0"}, shapesCommandInput, "invalid number of bins 0"},
+		{"unknown flag", []string{"-verbose"}, shapesCommandInput, "flag provided but not defined: -verbose"},
+		{"too many files", []string{"a.json", "b.json"}, shapesCommandInput, "too many arguments"},
+		{"missing file", []string{filepath.Join("testdata", "missing.json")}, "", "no such file"},
+		{"invalid JSON", nil, `[{"type": "circle"`, "reading shapes"},
+		{"invalid shape", nil, `[{"type": "circle", "radius": -1}]`, "invalid circle"},
+		{"unknown shape", nil, `[{"type": "hexagon", "side": 1}]`, `unknown shape type "hexagon"`},
+		{"no shapes", nil, `[]`, "no shapes"},
+	} {
+		var stdout, stderr bytes.Buffer
+		err := shapesCommand(c.args, strings.NewReader(c.input), &stdout, &stderr)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: shapes %v = %v, want an error containing %q", c.name, c.args, err, c.want)
+		}
+		if stdout.Len() > 0 {
+			t.Errorf("%s: shapes %v wrote to stdout: %s", c.name, c.args, stdout.String())
+		}
+	}
+
+	// `-help` prints the usage, without failing
+	var stdout, stderr bytes.Buffer
+	if err := shapesCommand([]string{"-help"}, strings.NewReader(""), &stdout, &stderr); err != nil || !strings.HasPrefix(stderr.String(), "usage: shapes") {
+		t.Errorf("shapes -help = %v, printed %q, want the usage and no error", err, stderr.String())
+	}
+}
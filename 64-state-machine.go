@@ -0,0 +1,260 @@
+// `transition` of `19-enums` chapter hard-codes a single next state per state, and panics on an unknown state.
+// A real server changes state because something happens (it connects, fails, retries, is reset): the next state
+// depends on both the current state AND the event.
+//
+// Here the rules are data instead of code: a table of `(state, event) --> next state`, which can be read at a glance,
+// printed, and checked exhaustively (see `64-state-machine_test.go`). On top of it:
+//   - guards: a transition can be refused depending on conditions (ex: no more retries allowed)
+//   - entry/exit hooks: functions called when entering/leaving a state (ex: start/stop a timer)
+//   - an error instead of a panic for an event not allowed in the current state
+//   - a history of all the transitions (and refused events), to understand how the machine got where it is
+//
+// The machine is generic (any state & event types), the `ServerState` rules being just 1 table.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// `String`, `ParseServerEvent`, etc. are generated in `serverevent_enum.go` (see `19-enums` chapter)
+//
+//go:generate go run ./cmd/enumgen -type=ServerEvent -trimprefix=Event
+type ServerEvent int
+
+const (
+	EventConnect ServerEvent = iota
+	EventFail
+	EventRetry
+	EventReset
+)
+
+// Matched by every `*TransitionError` with `errors.Is`
+var ErrIllegalTransition = errors.New("illegal transition")
+
+// An event that couldn't be applied: no transition for it in the current state, or refused by the guard
+type TransitionError[S, E comparable] struct {
+	From  S
+	Event E
+	// The guard's error (nil if there is no transition at all)
+	Reason error
+}
+
+func (e *TransitionError[S, E]) Error() string {
+	if e.Reason != nil {
+		return fmt.Sprintf("event %v refused in state %v: %v", e.Event, e.From, e.Reason)
+	}
+	return fmt.Sprintf("no transition for event %v in state %v", e.Event, e.From)
+}
+
+func (e *TransitionError[S, E]) Is(target error) bool {
+	return target == ErrIllegalTransition
+}
+
+func (e *TransitionError[S, E]) Unwrap() error {
+	return e.Reason
+}
+
+type Transition[S, E comparable] struct {
+	From  S
+	Event E
+	To    S
+	// Optional: returns an error to refuse the transition
+	Guard func() error
+}
+
+type transitionKey[S, E comparable] struct {
+	from  S
+	event E
+}
+
+// An entry of the history: a transition done, or an event refused (`Err` set, `To` = `From`)
+type HistoryEntry[S, E comparable] struct {
+	At    time.Time
+	From  S
+	Event E
+	To    S
+	Err   error
+}
+
+func (h HistoryEntry[S, E]) String() string {
+	if h.Err != nil {
+		return fmt.Sprintf("%v --%v--> refused: %v", h.From, h.Event, h.Err)
+	}
+	return fmt.Sprintf("%v --%v--> %v", h.From, h.Event, h.To)
+}
+
+// Not safe for concurrent use: like `serverActor` of `50-actors` chapter, own it from a single goroutine
+type StateMachine[S, E comparable] struct {
+	state       S
+	transitions map[transitionKey[S, E]]Transition[S, E]
+	onEnter     map[S][]func(from S, event E)
+	onExit      map[S][]func(to S, event E)
+	history     []HistoryEntry[S, E]
+}
+
+// Returns an error if the table has 2 transitions for the same (state, event): which one to take would be ambiguous
+func NewStateMachine[S, E comparable](initial S, table []Transition[S, E]) (*StateMachine[S, E], error) {
+	m := &StateMachine[S, E]{
+		state:       initial,
+		transitions: map[transitionKey[S, E]]Transition[S, E]{},
+		onEnter:     map[S][]func(S, E){},
+		onExit:      map[S][]func(S, E){},
+	}
+	for _, t := range table {
+		key := transitionKey[S, E]{t.From, t.Event}
+		if _, ok := m.transitions[key]; ok {
+			return nil, fmt.Errorf("duplicate transition for event %v in state %v", t.Event, t.From)
+		}
+		m.transitions[key] = t
+	}
+	return m, nil
+}
+
+// Registers a function called when entering `state` (after leaving the previous one)
+func (m *StateMachine[S, E]) OnEnter(state S, hook func(from S, event E)) {
+	m.onEnter[state] = append(m.onEnter[state], hook)
+}
+
+// Registers a function called when leaving `state`
+func (m *StateMachine[S, E]) OnExit(state S, hook func(to S, event E)) {
+	m.onExit[state] = append(m.onExit[state], hook)
+}
+
+func (m *StateMachine[S, E]) State() S {
+	return m.state
+}
+
+// Whether `event` has a transition in the current state (its guard isn't checked)
+func (m *StateMachine[S, E]) Can(event E) bool {
+	_, ok := m.transitions[transitionKey[S, E]{m.state, event}]
+	return ok
+}
+
+// Applies `event`: moves to the next state (calling the exit hooks of the current state, then the entry hooks
+// of the next one), or returns a `*TransitionError` and stays in the current state.
+//
+// Fyi, a transition to the same state (ex: a self-loop) still calls the exit & entry hooks.
+func (m *StateMachine[S, E]) Fire(event E) error {
+	from := m.state
+	t, ok := m.transitions[transitionKey[S, E]{from, event}]
+	var err error
+	if !ok {
+		err = &TransitionError[S, E]{From: from, Event: event}
+	} else if t.Guard != nil {
+		if reason := t.Guard(); reason != nil {
+			err = &TransitionError[S, E]{From: from, Event: event, Reason: reason}
+		}
+	}
+	if err != nil {
+		m.history = append(m.history, HistoryEntry[S, E]{At: time.Now(), From: from, Event: event, To: from, Err: err})
+		return err
+	}
+
+	for _, hook := range m.onExit[from] {
+		hook(t.To, event)
+	}
+	m.state = t.To
+	m.history = append(m.history, HistoryEntry[S, E]{At: time.Now(), From: from, Event: event, To: t.To})
+	for _, hook := range m.onEnter[t.To] {
+		hook(from, event)
+	}
+	return nil
+}
+
+// Copy of the history: the caller can't modify the machine's one
+func (m *StateMachine[S, E]) History() []HistoryEntry[S, E] {
+	return append([]HistoryEntry[S, E](nil), m.history...)
+}
+
+// The server's rules
+
+var ErrTooManyRetries = errors.New("too many retries")
+
+// The server's state machine, allowing `maxRetries` retries in a row (a successful connection resets the count)
+func NewServerStateMachine(maxRetries int) *StateMachine[ServerState, ServerEvent] {
+	retries := 0
+	table := []Transition[ServerState, ServerEvent]{
+		{From: StateIdle, Event: EventConnect, To: StateConnected},
+		{From: StateIdle, Event: EventFail, To: StateError},
+		{From: StateConnected, Event: EventFail, To: StateError},
+		{From: StateConnected, Event: EventReset, To: StateIdle},
+		{From: StateError, Event: EventRetry, To: StateRetrying, Guard: func() error {
+			if retries >= maxRetries {
+				return fmt.Errorf("%w (%d)", ErrTooManyRetries, maxRetries)
+			}
+			return nil
+		}},
+		{From: StateError, Event: EventReset, To: StateIdle},
+		{From: StateRetrying, Event: EventConnect, To: StateConnected},
+		{From: StateRetrying, Event: EventFail, To: StateError},
+		{From: StateRetrying, Event: EventReset, To: StateIdle},
+	}
+	m, err := NewStateMachine(StateIdle, table)
+	if err != nil {
+		// The table above is fixed: an error here is a bug
+		panic(err)
+	}
+
+	m.OnEnter(StateRetrying, func(ServerState, ServerEvent) { retries++ })
+	m.OnEnter(StateConnected, func(ServerState, ServerEvent) { retries = 0 })
+	m.OnEnter(StateIdle, func(ServerState, ServerEvent) { retries = 0 })
+	return m
+}
+
+func state_machine_main() {
+	m := NewServerStateMachine(2)
+	m.OnExit(StateConnected, func(to ServerState, e ServerEvent) { fmt.Printf("  (connection closed by %q)\n", e) })
+	m.OnEnter(StateConnected, func(from ServerState, e ServerEvent) { fmt.Printf("  (connected, coming from %q)\n", from) })
+
+	// The 3rd retry in a row is refused, until a reset
+	for _, e := range []ServerEvent{
+		EventConnect, EventFail, EventRetry, EventConnect,
+		EventFail, EventRetry, EventFail, EventRetry, EventFail, EventRetry,
+		EventConnect, EventReset, EventConnect,
+	} {
+		if err := m.Fire(e); err != nil {
+			fmt.Println("error:", err, "- too many retries:", errors.Is(err, ErrTooManyRetries))
+		}
+	}
+	fmt.Println("history:")
+	for _, h := range m.History() {
+		fmt.Println(" ", h)
+	}
+
+	// The transition table, as a matrix: state (row) x event (column)
+	states, events := ServerStateValues(), ServerEventValues()
+	table := NewServerStateMachine(0).transitions
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%-10s", "")
+	for _, e := range events {
+		fmt.Fprintf(&sb, "%-11s", e)
+	}
+	sb.WriteByte('\n')
+	for _, s := range states {
+		fmt.Fprintf(&sb, "%-10s", s)
+		for _, e := range events {
+			t, ok := table[transitionKey[ServerState, ServerEvent]{s, e}]
+			if ok {
+				fmt.Fprintf(&sb, "%-11s", t.To)
+			} else {
+				fmt.Fprintf(&sb, "%-11s", "-")
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	fmt.Print(sb.String())
+
+	// Unlike `transition`, an unknown state doesn't panic
+	unknown := NewServerStateMachine(1)
+	unknown.state = ServerState(42)
+	fmt.Println(unknown.Fire(EventConnect))
+
+	_, err := NewStateMachine(StateIdle, []Transition[ServerState, ServerEvent]{
+		{From: StateIdle, Event: EventConnect, To: StateConnected},
+		{From: StateIdle, Event: EventConnect, To: StateError},
+	})
+	fmt.Println(err)
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// Literal lists, so that the tests don't depend on the generated `ServerStateValues`/`ServerEventValues`
+var (
+	serverStates = []ServerState{StateIdle, StateConnected, StateError, StateRetrying}
+	serverEvents = []ServerEvent{EventConnect, EventFail, EventRetry, EventReset}
+)
+
+// Marks an event not allowed in a state
+const illegal ServerState = -1
+
+// The expected rules, written independently of `NewServerStateMachine`'s table: every (state, event) pair,
+// with its next state or `illegal`
+var wantServerTransitions = map[ServerState]map[ServerEvent]ServerState{
+	StateIdle: {
+		EventConnect: StateConnected,
+		EventFail:    StateError,
+		EventRetry:   illegal,
+		EventReset:   illegal,
+	},
+	StateConnected: {
+		EventConnect: illegal,
+		EventFail:    StateError,
+		EventRetry:   illegal,
+		EventReset:   StateIdle,
+	},
+	StateError: {
+		EventConnect: illegal,
+		EventFail:    illegal,
+		EventRetry:   StateRetrying,
+		EventReset:   StateIdle,
+	},
+	StateRetrying: {
+		EventConnect: StateConnected,
+		EventFail:    StateError,
+		EventRetry:   illegal,
+		EventReset:   StateIdle,
+	},
+}
+
+// From any state (placed there directly), any event either moves the machine as expected, or returns an
+// `ErrIllegalTransition` and leaves it where it was
+func TestServerStateMachineAllPairs(t *testing.T) {
+	if len(wantServerTransitions) != len(serverStates) {
+		t.Fatalf("expected table has %d states, want all %d", len(wantServerTransitions), len(serverStates))
+	}
+	for _, s := range serverStates {
+		if len(wantServerTransitions[s]) != len(serverEvents) {
+			t.Fatalf("expected table has %d events for state %d, want all %d", len(wantServerTransitions[s]), s, len(serverEvents))
+		}
+		for _, e := range serverEvents {
+			want := wantServerTransitions[s][e]
+			m := NewServerStateMachine(1)
+			m.state = s
+			can := m.Can(e)
+			err := m.Fire(e)
+
+			if want == illegal {
+				var te *TransitionError[ServerState, ServerEvent]
+				if can || !errors.As(err, &te) || te.From != s || te.Event != e || te.Reason != nil {
+					t.Errorf("state %d, event %d: Can = %v, Fire = %v, want false and an illegal transition", s, e, can, err)
+				}
+				if m.State() != s {
+					t.Errorf("state %d, event %d: moved to %d, want to stay", s, e, m.State())
+				}
+				continue
+			}
+			// No guard can refuse here: no retry was done yet
+			if !can || err != nil || m.State() != want {
+				t.Errorf("state %d, event %d: Can = %v, Fire = %v, state %d, want true, nil, %d", s, e, can, err, m.State(), want)
+			}
+		}
+	}
+}
+
+func TestServerStateMachineRetriesGuard(t *testing.T) {
+	m := NewServerStateMachine(2)
+	fire := func(events ...ServerEvent) {
+		t.Helper()
+		for _, e := range events {
+			if err := m.Fire(e); err != nil {
+				t.Fatalf("Fire(%d) in state %d = %v", e, m.State(), err)
+			}
+		}
+	}
+
+	// 2 retries in a row are allowed, not a 3rd one
+	fire(EventFail, EventRetry, EventFail, EventRetry, EventFail)
+	err := m.Fire(EventRetry)
+	var te *TransitionError[ServerState, ServerEvent]
+	if !errors.Is(err, ErrTooManyRetries) || !errors.Is(err, ErrIllegalTransition) || !errors.As(err, &te) ||
+		te.From != StateError || te.Event != EventRetry {
+		t.Fatalf("3rd retry = %v, want %v", err, ErrTooManyRetries)
+	}
+	if m.State() != StateError {
+		t.Errorf("state after the refused retry = %d, want %d", m.State(), StateError)
+	}
+	// Still refused: the count only goes down on a connection or a reset
+	if err := m.Fire(EventRetry); !errors.Is(err, ErrTooManyRetries) {
+		t.Errorf("retry again = %v, want %v", err, ErrTooManyRetries)
+	}
+
+	// A reset starts counting again
+	fire(EventReset, EventFail, EventRetry, EventFail, EventRetry)
+	// So does a successful connection
+	fire(EventConnect, EventFail, EventRetry, EventFail, EventRetry)
+	if m.State() != StateRetrying {
+		t.Errorf("state = %d, want %d", m.State(), StateRetrying)
+	}
+
+	// No retry allowed at all
+	m = NewServerStateMachine(0)
+	fire(EventFail)
+	if err := m.Fire(EventRetry); !errors.Is(err, ErrTooManyRetries) || m.State() != StateError {
+		t.Errorf("retry with 0 allowed = %v in state %d, want %v in state %d", err, m.State(), ErrTooManyRetries, StateError)
+	}
+}
+
+// A hook call, recorded by `TestStateMachineHooks`
+type hookCall struct {
+	hook string
+	// The state whose hook it is, and the other end of the transition
+	state, other ServerState
+	event        ServerEvent
+	// The machine's state when the hook was called
+	current ServerState
+}
+
+func TestStateMachineHooks(t *testing.T) {
+	m := NewServerStateMachine(1)
+	var calls []hookCall
+	for _, s := range serverStates {
+		m.OnExit(s, func(to ServerState, e ServerEvent) {
+			calls = append(calls, hookCall{"exit", s, to, e, m.State()})
+		})
+		m.OnEnter(s, func(from ServerState, e ServerEvent) {
+			calls = append(calls, hookCall{"enter", s, from, e, m.State()})
+		})
+	}
+	// Several hooks of a state are called in registration order
+	m.OnEnter(StateConnected, func(from ServerState, e ServerEvent) {
+		calls = append(calls, hookCall{"enter 2", StateConnected, from, e, m.State()})
+	})
+
+	m.Fire(EventConnect)
+	// Refused events call no hook
+	m.Fire(EventConnect)
+	m.Fire(EventReset)
+
+	want := []hookCall{
+		// Exit hooks see the state being left, entry hooks the state entered
+		{"exit", StateIdle, StateConnected, EventConnect, StateIdle},
+		{"enter", StateConnected, StateIdle, EventConnect, StateConnected},
+		{"enter 2", StateConnected, StateIdle, EventConnect, StateConnected},
+		{"exit", StateConnected, StateIdle, EventReset, StateConnected},
+		{"enter", StateIdle, StateConnected, EventReset, StateIdle},
+	}
+	if !slices.Equal(calls, want) {
+		t.Errorf("hook calls =\n%+v\nwant\n%+v", calls, want)
+	}
+}
+
+func TestStateMachineHistory(t *testing.T) {
+	m := NewServerStateMachine(1)
+	for _, e := range []ServerEvent{EventConnect, EventFail, EventRetry, EventFail, EventRetry, EventReset} {
+		m.Fire(e)
+	}
+
+	history := m.History()
+	want := []HistoryEntry[ServerState, ServerEvent]{
+		{From: StateIdle, Event: EventConnect, To: StateConnected},
+		{From: StateConnected, Event: EventFail, To: StateError},
+		{From: StateError, Event: EventRetry, To: StateRetrying},
+		{From: StateRetrying, Event: EventFail, To: StateError},
+		// Refused events are recorded too, staying in the same state
+		{From: StateError, Event: EventRetry, To: StateError, Err: ErrTooManyRetries},
+		{From: StateError, Event: EventReset, To: StateIdle},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("history = %v, want %d entries", history, len(want))
+	}
+	for i, h := range history {
+		w := want[i]
+		if h.From != w.From || h.Event != w.Event || h.To != w.To || (h.Err == nil) != (w.Err == nil) ||
+			(w.Err != nil && !errors.Is(h.Err, w.Err)) {
+			t.Errorf("history[%d] = %+v, want %+v", i, h, w)
+		}
+		if h.At.IsZero() || (i > 0 && h.At.Before(history[i-1].At)) {
+			t.Errorf("history[%d] at %v, want a time after the previous entry's", i, h.At)
+		}
+	}
+
+	// A copy: modifying it doesn't modify the machine's
+	history[0].To = StateError
+	if m.History()[0].To != StateConnected {
+		t.Error("modifying History() modified the machine's history")
+	}
+}
+
+// Every state can be reached from `StateIdle` (breadth-first search over the table), and can go back to it
+func TestServerStatesReachable(t *testing.T) {
+	table := NewServerStateMachine(0).transitions
+	reached := map[ServerState]bool{StateIdle: true}
+	queue := []ServerState{StateIdle}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, e := range serverEvents {
+			if tr, ok := table[transitionKey[ServerState, ServerEvent]{s, e}]; ok && !reached[tr.To] {
+				reached[tr.To] = true
+				queue = append(queue, tr.To)
+			}
+		}
+	}
+	for _, s := range serverStates {
+		if !reached[s] {
+			t.Errorf("state %d not reachable from %d", s, StateIdle)
+		}
+		if s == StateIdle {
+			continue
+		}
+		if tr, ok := table[transitionKey[ServerState, ServerEvent]{s, EventReset}]; !ok || tr.To != StateIdle {
+			t.Errorf("state %d doesn't go back to %d on a reset", s, StateIdle)
+		}
+	}
+}
+
+// Unlike `transition` of `19-enums` chapter, an unknown state doesn't panic
+func TestServerStateMachineUnknownState(t *testing.T) {
+	m := NewServerStateMachine(1)
+	m.state = ServerState(42)
+	if err := m.Fire(EventConnect); !errors.Is(err, ErrIllegalTransition) {
+		t.Errorf("Fire in an unknown state = %v, want %v", err, ErrIllegalTransition)
+	}
+}
+
+func TestNewStateMachineAmbiguousTable(t *testing.T) {
+	_, err := NewStateMachine(StateIdle, []Transition[ServerState, ServerEvent]{
+		{From: StateIdle, Event: EventConnect, To: StateConnected},
+		{From: StateIdle, Event: EventConnect, To: StateError},
+	})
+	if err == nil {
+		t.Error("NewStateMachine with 2 transitions for the same (state, event) = nil error")
+	}
+}
@@ -0,0 +1,347 @@
+// `enumgen` generates the methods of an enum (an integer type with its constants, see `19-enums` chapter), so that
+// they don't have to be written & kept up to date by hand:
+//   - `String()`, `IsValid()`, `ParseXxx(name)` and `XxxValues()`
+//   - `MarshalText`/`UnmarshalText` & `MarshalJSON`/`UnmarshalJSON`: the enum is written as its name (`"idle"`)
+//     rather than its number, and unknown names/numbers are errors
+//
+// It's meant to be run by `go generate` (like `stringer`, its standard counterpart), with a comment next to the type:
+//
+//	//go:generate go run ./cmd/enumgen -type=ServerState -trimprefix=State
+//
+// `go generate` runs the command in the directory of the file holding the comment: `enumgen` reads all the Go
+// files there, finds the type & its constants, and writes `<type>_enum.go` next to them.
+//
+// Each constant's name is its identifier without the prefix, in snake_case (`StateIdle` --> `idle`), or its line
+// comment with `-linecomment`. Generation fails if a constant ends up without a name, if 2 constants have the same
+// name, or the same value (an alias would make `String()` ambiguous).
+//
+// The constants' values are computed by the type checker (`go/types`): `iota`, expressions (`1 << iota`),
+// skipped values (`_`), conversions (`X = ServerState(3)`), other constants of the package, etc. all work like in
+// the compiler. Only the package's type & constant declarations are checked though, without loading its imports:
+// a constant of the enum can't depend on an imported package (ex: `X ServerState = ServerState(time.Second)`),
+// which is reported as an error.
+//
+// See `main_test.go` for the cases handled and the errors.
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+type enumConst struct {
+	Ident string
+	Name  string
+	Value int64
+}
+
+type enum struct {
+	Package string
+	Type    string
+	Args    string
+	Consts  []enumConst
+}
+
+func main() {
+	typeName := flag.String("type", "", "name of the enum type (required)")
+	trimPrefix := flag.String("trimprefix", "", "prefix removed from the constants' identifiers to make their names")
+	lineComment := flag.Bool("linecomment", false, "use the constants' line comments as names")
+	output := flag.String("output", "", "output file (default <type>_enum.go, lowercase)")
+	flag.Parse()
+	if *typeName == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	if *output == "" {
+		*output = filepath.Join(dir, strings.ToLower(*typeName)+"_enum.go")
+	}
+
+	e, err := findEnum(dir, *typeName, *trimPrefix, *lineComment, *output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "enumgen:", err)
+		os.Exit(1)
+	}
+	e.Args = strings.Join(os.Args[1:], " ")
+
+	src, err := generate(e)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "enumgen:", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "enumgen:", err)
+		os.Exit(1)
+	}
+}
+
+// Reads the Go files of `dir` (except tests & the previous output) and collects the constants of type `typeName`
+func findEnum(dir, typeName, trimPrefix string, lineComment bool, output string) (enum, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return enum{}, err
+	}
+
+	fset := token.NewFileSet()
+	var pkgName string
+	var found bool
+	// Only the type & constant declarations of the package
+	decls := &ast.File{}
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") || filepath.Clean(path) == filepath.Clean(output) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return enum{}, err
+		}
+		pkgName = file.Name.Name
+
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || (gen.Tok != token.TYPE && gen.Tok != token.CONST) {
+				continue
+			}
+			decls.Decls = append(decls.Decls, gen)
+			if gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				found = found || spec.(*ast.TypeSpec).Name.Name == typeName
+			}
+		}
+	}
+	if !found {
+		return enum{}, fmt.Errorf("type %s not found in %s", typeName, dir)
+	}
+
+	// Type-checks those declarations alone: functions (which may call the methods about to be generated) are left out,
+	// and imports aren't loaded. The errors this causes elsewhere in the package (ex: a constant of an imported type)
+	// are collected instead of stopping the checker, and only reported if our enum is affected.
+	decls.Name = ast.NewIdent(pkgName)
+	var typeErrs []error
+	info := &types.Info{Defs: map[*ast.Ident]types.Object{}}
+	conf := &types.Config{Error: func(err error) { typeErrs = append(typeErrs, err) }}
+	pkg, _ := conf.Check(pkgName, fset, []*ast.File{decls}, info)
+	typeCheckErr := func() error {
+		return fmt.Errorf("type-checking %s and its constants: %w", typeName, errors.Join(typeErrs...))
+	}
+	named := pkg.Scope().Lookup(typeName).Type()
+	switch basic, ok := named.Underlying().(*types.Basic); {
+	case ok && basic.Kind() == types.Invalid:
+		// Ex: `type ServerState time.Duration`
+		return enum{}, typeCheckErr()
+	case !ok || basic.Info()&types.IsInteger == 0:
+		return enum{}, fmt.Errorf("type %s is not an integer type", typeName)
+	}
+
+	e := enum{Package: pkgName, Type: typeName}
+	var errs []error
+	names, values := map[string]string{}, map[int64]string{}
+	for _, decl := range decls.Decls {
+		decl := decl.(*ast.GenDecl)
+		if decl.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range decl.Specs {
+			spec := spec.(*ast.ValueSpec)
+			for _, ident := range spec.Names {
+				c, ok := info.Defs[ident].(*types.Const)
+				if ident.Name == "_" || !ok {
+					continue
+				}
+				// Depends on something the checker couldn't see (ex: an imported constant): its type is unknown
+				// for `X = ServerState(time.Second)`, its value for `X ServerState = ServerState(time.Second)`
+				if (c.Type() == types.Typ[types.Invalid] && mentions(spec, typeName)) ||
+					(types.Identical(c.Type(), named) && c.Val().Kind() == constant.Unknown) {
+					return enum{}, typeCheckErr()
+				}
+				// Whatever the way its type is given: `X ServerState = 1`, implicitly repeated in an `iota` block,
+				// or a conversion `X = ServerState(1)`
+				if !types.Identical(c.Type(), named) {
+					continue
+				}
+
+				// A uint64 constant above math.MaxInt64 doesn't fit: its generated value would be wrong
+				value, exact := constant.Int64Val(c.Val())
+				if !exact {
+					errs = append(errs, fmt.Errorf("%s: constant %s value %s doesn't fit in an int64", fset.Position(ident.Pos()), ident.Name, c.Val()))
+					continue
+				}
+				name := snakeCase(strings.TrimPrefix(ident.Name, trimPrefix))
+				if lineComment {
+					name = ""
+					if spec.Comment != nil {
+						name = strings.TrimSpace(spec.Comment.Text())
+					}
+				}
+
+				switch {
+				case name == "":
+					errs = append(errs, fmt.Errorf("%s: constant %s has no name", fset.Position(ident.Pos()), ident.Name))
+				case names[name] != "":
+					errs = append(errs, fmt.Errorf("%s: constants %s and %s have the same name %q", fset.Position(ident.Pos()), names[name], ident.Name, name))
+				case values[value] != "":
+					errs = append(errs, fmt.Errorf("%s: constants %s and %s have the same value %d", fset.Position(ident.Pos()), values[value], ident.Name, value))
+				}
+				names[name], values[value] = ident.Name, ident.Name
+				e.Consts = append(e.Consts, enumConst{Ident: ident.Name, Name: name, Value: value})
+			}
+		}
+	}
+	if len(e.Consts) == 0 {
+		errs = append(errs, fmt.Errorf("no constants of type %s found", typeName))
+	}
+	if len(errs) > 0 {
+		return enum{}, errors.Join(errs...)
+	}
+
+	slices.SortFunc(e.Consts, func(a, b enumConst) int { return cmp.Compare(a.Value, b.Value) })
+	return e, nil
+}
+
+// Whether `typeName` appears in the type or values of `spec`
+func mentions(spec *ast.ValueSpec, typeName string) bool {
+	found := false
+	ast.Inspect(spec, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident.Name == typeName {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// `NotFound` --> `not_found`, `HTTPError` --> `http_error`
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			// End of an acronym: the `E` of `HTTPError`
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				sb.WriteByte('_')
+			}
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
+
+var enumTemplate = template.Must(template.New("enum").Parse(`// Code generated by "enumgen {{.Args}}"; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Compile error ("invalid array index") if the constants' values changed since the generation: run ` + "`go generate`" + ` again
+func _() {
+	var x [1]struct{}
+{{- range .Consts}}
+	_ = x[{{.Ident}}-({{.Value}})]
+{{- end}}
+}
+
+// Every {{.Type}} constant, in ascending order
+func {{.Type}}Values() []{{.Type}} {
+	return []{{.Type}}{ {{- range $i, $c := .Consts}}{{if $i}}, {{end}}{{$c.Ident}}{{end -}} }
+}
+
+// Whether the value is one of the {{.Type}} constants
+func (v {{.Type}}) IsValid() bool {
+	switch v {
+	case {{range $i, $c := .Consts}}{{if $i}}, {{end}}{{$c.Ident}}{{end}}:
+		return true
+	}
+	return false
+}
+
+func (v {{.Type}}) String() string {
+	switch v {
+{{- range .Consts}}
+	case {{.Ident}}:
+		return {{printf "%q" .Name}}
+{{- end}}
+	}
+	return fmt.Sprintf("{{.Type}}(%d)", int64(v))
+}
+
+// The {{.Type}} constant named ` + "`name`" + ` (as returned by ` + "`String`" + `)
+func Parse{{.Type}}(name string) ({{.Type}}, error) {
+	switch name {
+{{- range .Consts}}
+	case {{printf "%q" .Name}}:
+		return {{.Ident}}, nil
+{{- end}}
+	}
+	return 0, fmt.Errorf("invalid {{.Type}} %q", name)
+}
+
+func (v {{.Type}}) MarshalText() ([]byte, error) {
+	if !v.IsValid() {
+		return nil, fmt.Errorf("invalid {{.Type}} %d", int64(v))
+	}
+	return []byte(v.String()), nil
+}
+
+func (v *{{.Type}}) UnmarshalText(text []byte) error {
+	parsed, err := Parse{{.Type}}(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func (v {{.Type}}) MarshalJSON() ([]byte, error) {
+	text, err := v.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+func (v *{{.Type}}) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("{{.Type}} should be a string, got %s", data)
+	}
+	return v.UnmarshalText([]byte(name))
+}
+`))
+
+func generate(e enum) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := enumTemplate.Execute(&buf, e); err != nil {
+		return nil, err
+	}
+	// gofmt-ed, like any Go file
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, buf.Bytes())
+	}
+	return src, nil
+}
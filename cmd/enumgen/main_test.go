@@ -0,0 +1,209 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Runs `findEnum` for type `Color` over a package made of `files` (name --> source, without the package clause)
+func findColor(t *testing.T, files map[string]string, trimPrefix string, lineComment bool) (enum, error) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package colors\n\n"+src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return findEnum(dir, "Color", trimPrefix, lineComment, filepath.Join(dir, "color_enum.go"))
+}
+
+func TestFindEnum(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		files       map[string]string
+		trimPrefix  string
+		lineComment bool
+		want        []enumConst
+	}{
+		{
+			name:       "iota",
+			files:      map[string]string{"a.go": "type Color int\n\nconst (\n\tColorRed Color = iota\n\tColorDarkGreen\n\tColorBlue\n)\n"},
+			trimPrefix: "Color",
+			want:       []enumConst{{"ColorRed", "red", 0}, {"ColorDarkGreen", "dark_green", 1}, {"ColorBlue", "blue", 2}},
+		},
+		{
+			name:  "shifted iota",
+			files: map[string]string{"a.go": "type Color uint8\n\nconst (\n\tRed Color = 1 << iota\n\tGreen\n\tBlue\n)\n"},
+			want:  []enumConst{{"Red", "red", 1}, {"Green", "green", 2}, {"Blue", "blue", 4}},
+		},
+		{
+			name:  "skipped values",
+			files: map[string]string{"a.go": "type Color int\n\nconst (\n\t_ Color = iota\n\tRed\n\t_\n\tBlue\n)\n"},
+			want:  []enumConst{{"Red", "red", 1}, {"Blue", "blue", 3}},
+		},
+		{
+			name:        "line comments",
+			files:       map[string]string{"a.go": "type Color int\n\nconst (\n\tRed Color = iota // rouge\n\tGreen // vert\n)\n"},
+			lineComment: true,
+			want:        []enumConst{{"Red", "rouge", 0}, {"Green", "vert", 1}},
+		},
+		{
+			// Sorted by value, whatever the order of declaration
+			name: "conversions and several files",
+			files: map[string]string{
+				"a.go": "type Color int\n\nconst Blue = Color(7)\n",
+				"b.go": "const (\n\tRed Color = -1\n\tGreen = Color(3)\n)\n",
+			},
+			want: []enumConst{{"Red", "red", -1}, {"Green", "green", 3}, {"Blue", "blue", 7}},
+		},
+		{
+			name: "other constants of the package",
+			files: map[string]string{
+				"a.go": "type Color int\n\nconst (\n\tRed Color = base + iota\n\tGreen\n)\n",
+				"b.go": "const base = 2 * offset\n\nconst offset = 5\n",
+			},
+			want: []enumConst{{"Red", "red", 10}, {"Green", "green", 11}},
+		},
+		{
+			// What the checker can't see is ignored, as long as the enum doesn't depend on it
+			name: "rest of the package ignored",
+			files: map[string]string{
+				"a.go": "import \"time\"\n\ntype Color int\n\nconst Red Color = 0\n\nconst timeout = time.Second\n\ntype clock time.Time\n",
+				// Calling the methods about to be generated
+				"b.go": "func describe(c Color) string { return c.String() + undefinedFunc() }\n",
+				// The previous output is skipped, and so are tests
+				"color_enum.go": "this doesn't even parse",
+				"a_test.go":     "neither does this",
+			},
+			want: []enumConst{{"Red", "red", 0}},
+		},
+		{
+			name: "other enum types",
+			files: map[string]string{"a.go": "type Color int\n\ntype Size int\n\nconst (\n\tRed Color = iota\n\tGreen\n)\n\n" +
+				"const (\n\tSmall Size = iota\n\tLarge\n\tRedish = Color(2)\n)\n\nconst untyped = 3\n"},
+			want: []enumConst{{"Red", "red", 0}, {"Green", "green", 1}, {"Redish", "redish", 2}},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			e, err := findColor(t, c.files, c.trimPrefix, c.lineComment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Package != "colors" || e.Type != "Color" || !slices.Equal(e.Consts, c.want) {
+				t.Errorf("findEnum = %+v, want package colors, type Color and %+v", e, c.want)
+			}
+		})
+	}
+}
+
+func TestFindEnumErrors(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		files       map[string]string
+		lineComment bool
+		// Every line must be in the error
+		want []string
+	}{
+		{
+			name:        "no name",
+			files:       map[string]string{"a.go": "type Color int\n\nconst (\n\tRed Color = iota // red\n\tGreen\n)\n"},
+			lineComment: true,
+			want:        []string{"a.go:7:2: constant Green has no name"},
+		},
+		{
+			name:        "duplicate name",
+			files:       map[string]string{"a.go": "type Color int\n\nconst (\n\tRed Color = iota // red\n\tScarlet // red\n)\n"},
+			lineComment: true,
+			want:        []string{`a.go:7:2: constants Red and Scarlet have the same name "red"`},
+		},
+		{
+			name:  "duplicate value",
+			files: map[string]string{"a.go": "type Color int\n\nconst (\n\tRed Color = 1\n\tGreen Color = 2\n\tScarlet = Red\n)\n"},
+			want:  []string{"a.go:8:2: constants Red and Scarlet have the same value 1"},
+		},
+		{
+			name:  "int64 overflow",
+			files: map[string]string{"a.go": "type Color uint64\n\nconst (\n\tRed Color = 1 << 63\n\tGreen Color = 1\n)\n"},
+			want:  []string{"a.go:6:2: constant Red value 9223372036854775808 doesn't fit in an int64"},
+		},
+		{
+			// All the errors at once
+			name:  "several errors",
+			files: map[string]string{"a.go": "type Color int\n\nconst (\n\tRed Color = 1\n\tRED Color = 2\n\tBlue Color = 1\n)\n"},
+			want:  []string{`constants Red and RED have the same name "red"`, "constants Red and Blue have the same value 1"},
+		},
+		{
+			name:  "no constants",
+			files: map[string]string{"a.go": "type Color int\n\nconst Red = 1\n\nvar Green Color = 2\n"},
+			want:  []string{"no constants of type Color found"},
+		},
+		{
+			name:  "not an integer",
+			files: map[string]string{"a.go": "type Color string\n\nconst Red Color = \"red\"\n"},
+			want:  []string{"type Color is not an integer type"},
+		},
+		{
+			name:  "not a basic type",
+			files: map[string]string{"a.go": "type Color struct{}\n"},
+			want:  []string{"type Color is not an integer type"},
+		},
+		{
+			name:  "type not found",
+			files: map[string]string{"a.go": "type Colour int\n\nconst Red Colour = 1\n"},
+			want:  []string{"type Color not found"},
+		},
+		{
+			// The documented limit: imports aren't loaded
+			name:  "imported constant",
+			files: map[string]string{"a.go": "import \"time\"\n\ntype Color int\n\nconst Red = Color(time.Second)\n"},
+			want:  []string{"type-checking Color and its constants", "undefined: time"},
+		},
+		{
+			name:  "imported constant, explicit type",
+			files: map[string]string{"a.go": "import \"time\"\n\ntype Color int\n\nconst (\n\tRed Color = 1\n\tGreen Color = Color(time.Second)\n)\n"},
+			want:  []string{"type-checking Color and its constants", "undefined: time"},
+		},
+		{
+			name:  "imported type",
+			files: map[string]string{"a.go": "import \"time\"\n\ntype Color time.Duration\n"},
+			want:  []string{"type-checking Color and its constants", "undefined: time"},
+		},
+		{
+			name:  "syntax error",
+			files: map[string]string{"a.go": "type Color int\n\nconst Red Color =\n"},
+			want:  []string{"a.go:5:19: expected operand"},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			e, err := findColor(t, c.files, "", c.lineComment)
+			if err == nil {
+				t.Fatalf("findEnum = %+v, want an error", e)
+			}
+			for _, want := range c.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("findEnum error:\n%v\nwant it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := generate(enum{Package: "colors", Type: "Color", Args: "-type=Color", Consts: []enumConst{{"Red", "red", 1}, {"Blue", "blue", 4}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`// Code generated by "enumgen -type=Color"; DO NOT EDIT.`,
+		"\n\t_ = x[Blue-(4)]\n",
+		"return []Color{Red, Blue}",
+		"\tcase \"blue\":\n\t\treturn Blue, nil\n",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code doesn't contain %q:\n%s", want, src)
+		}
+	}
+}
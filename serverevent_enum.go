@@ -0,0 +1,92 @@
+// Code generated by "enumgen -type=ServerEvent -trimprefix=Event"; DO NOT EDIT.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Compile error ("invalid array index") if the constants' values changed since the generation: run `go generate` again
+func _() {
+	var x [1]struct{}
+	_ = x[EventConnect-(0)]
+	_ = x[EventFail-(1)]
+	_ = x[EventRetry-(2)]
+	_ = x[EventReset-(3)]
+}
+
+// Every ServerEvent constant, in ascending order
+func ServerEventValues() []ServerEvent {
+	return []ServerEvent{EventConnect, EventFail, EventRetry, EventReset}
+}
+
+// Whether the value is one of the ServerEvent constants
+func (v ServerEvent) IsValid() bool {
+	switch v {
+	case EventConnect, EventFail, EventRetry, EventReset:
+		return true
+	}
+	return false
+}
+
+func (v ServerEvent) String() string {
+	switch v {
+	case EventConnect:
+		return "connect"
+	case EventFail:
+		return "fail"
+	case EventRetry:
+		return "retry"
+	case EventReset:
+		return "reset"
+	}
+	return fmt.Sprintf("ServerEvent(%d)", int64(v))
+}
+
+// The ServerEvent constant named `name` (as returned by `String`)
+func ParseServerEvent(name string) (ServerEvent, error) {
+	switch name {
+	case "connect":
+		return EventConnect, nil
+	case "fail":
+		return EventFail, nil
+	case "retry":
+		return EventRetry, nil
+	case "reset":
+		return EventReset, nil
+	}
+	return 0, fmt.Errorf("invalid ServerEvent %q", name)
+}
+
+func (v ServerEvent) MarshalText() ([]byte, error) {
+	if !v.IsValid() {
+		return nil, fmt.Errorf("invalid ServerEvent %d", int64(v))
+	}
+	return []byte(v.String()), nil
+}
+
+func (v *ServerEvent) UnmarshalText(text []byte) error {
+	parsed, err := ParseServerEvent(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func (v ServerEvent) MarshalJSON() ([]byte, error) {
+	text, err := v.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+func (v *ServerEvent) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("ServerEvent should be a string, got %s", data)
+	}
+	return v.UnmarshalText([]byte(name))
+}
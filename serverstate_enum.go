@@ -0,0 +1,92 @@
+// Code generated by "enumgen -type=ServerState -trimprefix=State"; DO NOT EDIT.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Compile error ("invalid array index") if the constants' values changed since the generation: run `go generate` again
+func _() {
+	var x [1]struct{}
+	_ = x[StateIdle-(0)]
+	_ = x[StateConnected-(1)]
+	_ = x[StateError-(2)]
+	_ = x[StateRetrying-(3)]
+}
+
+// Every ServerState constant, in ascending order
+func ServerStateValues() []ServerState {
+	return []ServerState{StateIdle, StateConnected, StateError, StateRetrying}
+}
+
+// Whether the value is one of the ServerState constants
+func (v ServerState) IsValid() bool {
+	switch v {
+	case StateIdle, StateConnected, StateError, StateRetrying:
+		return true
+	}
+	return false
+}
+
+func (v ServerState) String() string {
+	switch v {
+	case StateIdle:
+		return "idle"
+	case StateConnected:
+		return "connected"
+	case StateError:
+		return "error"
+	case StateRetrying:
+		return "retrying"
+	}
+	return fmt.Sprintf("ServerState(%d)", int64(v))
+}
+
+// The ServerState constant named `name` (as returned by `String`)
+func ParseServerState(name string) (ServerState, error) {
+	switch name {
+	case "idle":
+		return StateIdle, nil
+	case "connected":
+		return StateConnected, nil
+	case "error":
+		return StateError, nil
+	case "retrying":
+		return StateRetrying, nil
+	}
+	return 0, fmt.Errorf("invalid ServerState %q", name)
+}
+
+func (v ServerState) MarshalText() ([]byte, error) {
+	if !v.IsValid() {
+		return nil, fmt.Errorf("invalid ServerState %d", int64(v))
+	}
+	return []byte(v.String()), nil
+}
+
+func (v *ServerState) UnmarshalText(text []byte) error {
+	parsed, err := ParseServerState(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func (v ServerState) MarshalJSON() ([]byte, error) {
+	text, err := v.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+func (v *ServerState) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("ServerState should be a string, got %s", data)
+	}
+	return v.UnmarshalText([]byte(name))
+}